@@ -0,0 +1,41 @@
+package reedsolomon
+
+import "unsafe"
+
+// cacheLineSize is the alignment of shards allocated by AllocAligned.
+const cacheLineSize = 64
+
+// AllocAligned allocates count shards of shardSize bytes in a single
+// buffer, for use with any function of the package.
+//
+// Every shard starts at a 64 byte (cache line) boundary, which also
+// satisfies the 32 byte alignment preferred by the AVX2 kernels.
+// Shards supplied by the caller do not need to be aligned, but
+// unaligned shards may be processed slower. Note that shards returned
+// by Split share memory with the input, so they are only aligned if
+// the input is and the shard size is a multiple of 64.
+//
+// The capacity of each shard is limited to shardSize, so appending
+// to a shard never overwrites the next.
+func AllocAligned(count, shardSize int) [][]byte {
+	stride := (shardSize + cacheLineSize - 1) &^ (cacheLineSize - 1)
+	buf := make([]byte, stride*count+cacheLineSize-1)
+	off := 0
+	if len(buf) > 0 {
+		off = int(-uintptr(unsafe.Pointer(&buf[0])) & (cacheLineSize - 1))
+	}
+	shards := make([][]byte, count)
+	for i := range shards {
+		start := off + i*stride
+		shards[i] = buf[start : start+shardSize : start+shardSize]
+	}
+	return shards
+}
+
+// AllocAligned allocates a complete set of shards of shardSize bytes,
+// ready to be filled with data and given to Encode.
+// The shards are aligned as described for the package level
+// AllocAligned function.
+func (r reedSolomon) AllocAligned(shardSize int) [][]byte {
+	return AllocAligned(r.Shards, shardSize)
+}
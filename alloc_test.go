@@ -0,0 +1,91 @@
+package reedsolomon
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestAllocAligned(t *testing.T) {
+	r, err := newExt(10, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{1, 63, 64, 65, 1000, 4096} {
+		shards := r.AllocAligned(size)
+		if len(shards) != 14 {
+			t.Fatalf("expected 14 shards, got %d", len(shards))
+		}
+		for i, shard := range shards {
+			if len(shard) != size || cap(shard) != size {
+				t.Fatalf("size %d: shard %d has len %d, cap %d", size, i, len(shard), cap(shard))
+			}
+			if uintptr(unsafe.Pointer(&shard[0]))%cacheLineSize != 0 {
+				t.Fatalf("size %d: shard %d is not aligned", size, i)
+			}
+		}
+		for i := 0; i < 10; i++ {
+			fillRandom(shards[i])
+		}
+		err = r.Encode(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok, err := r.Verify(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("verification failed")
+		}
+	}
+	shards := AllocAligned(3, 100)
+	for i, shard := range shards {
+		if len(shard) != 100 || uintptr(unsafe.Pointer(&shard[0]))%32 != 0 {
+			t.Fatalf("shard %d is not 32 byte aligned", i)
+		}
+	}
+	shards = r.AllocAligned(0)
+	if len(shards) != 14 || len(shards[0]) != 0 {
+		t.Fatal("unexpected result for size 0")
+	}
+}
+
+func benchmarkEncodeAligned(b *testing.B, dataShards, parityShards, shardSize int, aligned bool) {
+	r, err := New(dataShards, parityShards)
+	if err != nil {
+		b.Fatal(err)
+	}
+	shards := AllocAligned(dataShards+parityShards, shardSize+1)
+	for i := range shards {
+		if aligned {
+			shards[i] = shards[i][:shardSize]
+		} else {
+			shards[i] = shards[i][1:]
+		}
+		fillRandom(shards[i])
+	}
+	b.SetBytes(int64(shardSize * dataShards))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err = r.Encode(shards)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeAligned10x4x64K(b *testing.B) {
+	benchmarkEncodeAligned(b, 10, 4, 64<<10, true)
+}
+
+func BenchmarkEncodeUnaligned10x4x64K(b *testing.B) {
+	benchmarkEncodeAligned(b, 10, 4, 64<<10, false)
+}
+
+func BenchmarkEncodeAligned10x4x1M(b *testing.B) {
+	benchmarkEncodeAligned(b, 10, 4, 1<<20, true)
+}
+
+func BenchmarkEncodeUnaligned10x4x1M(b *testing.B) {
+	benchmarkEncodeAligned(b, 10, 4, 1<<20, false)
+}
@@ -0,0 +1,69 @@
+package reedsolomon
+
+import "time"
+
+// benchmarkDuration is the minimum time spent measuring each
+// operation in Benchmark.
+const benchmarkDuration = 100 * time.Millisecond
+
+// Benchmark measures the throughput of encoding and reconstructing
+// data on this machine, in MB/s (10^6 bytes per second) of data.
+//
+// An encoder is created with dataShards, parityShards and opts, so
+// the backend that will be used by New with the same options is
+// measured. Reconstruction is measured with as many data shards
+// missing as there are parity shards.
+// Each operation is run for at least 100ms, so the call takes
+// around 200ms.
+//
+// Zero is returned for both values if the encoder cannot be created
+// or shardSize is less than 1. The reconstruction speed is zero if
+// there are no parity shards.
+func Benchmark(dataShards, parityShards, shardSize int, opts ...Option) (encodeMBps, reconstructMBps float64) {
+	if shardSize < 1 {
+		return 0, 0
+	}
+	enc, err := New(dataShards, parityShards, opts...)
+	if err != nil {
+		return 0, 0
+	}
+	r := enc.(*reedSolomon)
+	shards := r.AllocAligned(shardSize)
+	for i := 0; i < dataShards; i++ {
+		for j := range shards[i] {
+			shards[i][j] = byte(i*31 + j*7)
+		}
+	}
+	dataSize := float64(dataShards * shardSize)
+
+	measure := func(fn func()) float64 {
+		n := 0
+		start := time.Now()
+		for time.Since(start) < benchmarkDuration || n == 0 {
+			fn()
+			n++
+		}
+		return dataSize * float64(n) / time.Since(start).Seconds() / 1e6
+	}
+
+	encodeMBps = measure(func() {
+		r.Encode(shards)
+	})
+	if parityShards == 0 {
+		return encodeMBps, 0
+	}
+
+	missing := parityShards
+	if missing > dataShards {
+		missing = dataShards
+	}
+	test := make([][]byte, len(shards))
+	reconstructMBps = measure(func() {
+		copy(test, shards)
+		for i := 0; i < missing; i++ {
+			test[i] = nil
+		}
+		r.Reconstruct(test)
+	})
+	return encodeMBps, reconstructMBps
+}
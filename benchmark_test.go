@@ -0,0 +1,26 @@
+package reedsolomon
+
+import "testing"
+
+func TestBenchmark(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	enc, rec := Benchmark(10, 4, 64<<10)
+	if enc <= 0 || rec <= 0 {
+		t.Errorf("expected positive throughput, got %v, %v", enc, rec)
+	}
+	t.Logf("encode: %.0f MB/s, reconstruct: %.0f MB/s", enc, rec)
+	enc, rec = Benchmark(4, 0, 1000, WithPureGo(true))
+	if enc <= 0 || rec != 0 {
+		t.Errorf("expected encode only, got %v, %v", enc, rec)
+	}
+	enc, rec = Benchmark(0, 4, 1000)
+	if enc != 0 || rec != 0 {
+		t.Errorf("expected zero for invalid shards, got %v, %v", enc, rec)
+	}
+	enc, rec = Benchmark(4, 4, 0)
+	if enc != 0 || rec != 0 {
+		t.Errorf("expected zero for invalid size, got %v, %v", enc, rec)
+	}
+}
@@ -0,0 +1,173 @@
+package reedsolomon
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// castagnoli is the CRC32C table used for all shard checksums.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ShardTag identifies the content of a single shard.
+// Tags are created by Tags after encoding, and should be stored
+// together with the shard, so the position of the shard can be
+// validated when reconstructing.
+type ShardTag struct {
+	Index int    // Index of the shard in the encoded set.
+	CRC   uint32 // CRC32C (Castagnoli) of the shard content.
+}
+
+// ErrInvalidTags is returned by ReconstructTagged if the tags
+// do not describe the shards given to the encoder.
+var ErrInvalidTags = errors.New("shard tags do not match the shard set")
+
+// ShardPositionError is returned by ReconstructTagged if one or more
+// shards do not match the tag recorded for their position.
+type ShardPositionError struct {
+	Positions []int // Positions of the shards that did not match their tag.
+	Origins   []int // Index the shard was tagged with, or -1 if it matched no tag.
+}
+
+// Error returns the error as a string
+func (s ShardPositionError) Error() string {
+	msgs := make([]string, len(s.Positions))
+	for i, pos := range s.Positions {
+		if s.Origins[i] < 0 {
+			msgs[i] = fmt.Sprintf("shard %d does not match any tag", pos)
+		} else {
+			msgs[i] = fmt.Sprintf("shard %d belongs at index %d", pos, s.Origins[i])
+		}
+	}
+	return "misplaced shards: " + strings.Join(msgs, ", ")
+}
+
+// Tags returns a tag for every shard in the set.
+// All shards must be present, so this should be called after
+// encoding.
+func (r reedSolomon) Tags(shards [][]byte) ([]ShardTag, error) {
+	if len(shards) != r.Shards {
+		return nil, ErrShardCount
+	}
+	err := checkShards(shards, false)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]ShardTag, len(shards))
+	for i, shard := range shards {
+		tags[i] = ShardTag{Index: i, CRC: crc32.Checksum(shard, castagnoli)}
+	}
+	return tags, nil
+}
+
+// ReconstructTagged will check that every present shard matches
+// the tag for its position, and then recreate the missing shards.
+//
+// If one or more shards do not match their tag a ShardPositionError
+// is returned, which will identify the shards that are misplaced,
+// and the shards are not modified. Otherwise this functions as
+// Reconstruct.
+func (r reedSolomon) ReconstructTagged(shards [][]byte, tags []ShardTag) error {
+	if len(shards) != r.Shards {
+		return ErrShardCount
+	}
+	if len(tags) != r.Shards {
+		return ErrInvalidTags
+	}
+	for i, tag := range tags {
+		if tag.Index != i {
+			return ErrInvalidTags
+		}
+	}
+
+	var perr ShardPositionError
+	for i, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		crc := crc32.Checksum(shard, castagnoli)
+		if crc == tags[i].CRC {
+			continue
+		}
+		origin := -1
+		for j, tag := range tags {
+			if tag.CRC == crc {
+				origin = j
+				break
+			}
+		}
+		perr.Positions = append(perr.Positions, i)
+		perr.Origins = append(perr.Origins, origin)
+	}
+	if len(perr.Positions) > 0 {
+		return perr
+	}
+	return r.reconstruct(shards, false)
+}
+
+// ChecksumSize is the number of bytes added to each shard by AttachChecksums.
+const ChecksumSize = crc32.Size
+
+// AttachChecksums will append a CRC32C (Castagnoli) checksum to
+// every shard in the set. The checksum is stored big endian in the
+// last ChecksumSize bytes of each shard.
+//
+// The checksum is not part of the coding, so shards with checksums
+// attached must be validated with ValidateChecksums before being
+// used with any other function.
+func (r reedSolomon) AttachChecksums(shards [][]byte) error {
+	if len(shards) != r.Shards {
+		return ErrShardCount
+	}
+	err := checkShards(shards, false)
+	if err != nil {
+		return err
+	}
+	for i, shard := range shards {
+		crc := crc32.Checksum(shard, castagnoli)
+		// Limit capacity, so we never write into a neighbouring shard,
+		// as is the case for shards returned by Split.
+		shard = shard[:len(shard):len(shard)]
+		shards[i] = append(shard, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+	}
+	return nil
+}
+
+// ValidateChecksums will check the checksums added by AttachChecksums.
+//
+// The indexes of the shards that do not match their checksum are
+// returned. Missing (nil) shards are skipped.
+// The checksum is removed from all shards that matched, while the
+// failed shards are left untouched. They should be set to nil before
+// the set is given to Reconstruct.
+func (r reedSolomon) ValidateChecksums(shards [][]byte) ([]int, error) {
+	if len(shards) != r.Shards {
+		return nil, ErrShardCount
+	}
+	if shardSize(shards) == 0 {
+		return nil, ErrShardNoData
+	}
+	var bad []int
+	for i, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		if len(shard) < ChecksumSize {
+			bad = append(bad, i)
+			continue
+		}
+		n := len(shard) - ChecksumSize
+		want := crc32.Checksum(shard[:n], castagnoli)
+		got := uint32(shard[n])<<24 | uint32(shard[n+1])<<16 | uint32(shard[n+2])<<8 | uint32(shard[n+3])
+		if got != want {
+			bad = append(bad, i)
+		}
+	}
+	for i, shard := range shards {
+		if len(shard) >= ChecksumSize && !contains(bad, i) {
+			shards[i] = shard[:len(shard)-ChecksumSize]
+		}
+	}
+	return bad, nil
+}
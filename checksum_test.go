@@ -0,0 +1,184 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestReconstructTagged(t *testing.T) {
+	perShard := 10000
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := make([][]byte, 13)
+	for s := range shards {
+		shards[s] = make([]byte, perShard)
+	}
+
+	rand.Seed(0)
+	for s := 0; s < 10; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := r.Tags(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Swap two shards and remove one.
+	shard1 := shards[1]
+	shards[1], shards[4] = shards[4], shards[1]
+	shards[0] = nil
+	err = r.ReconstructTagged(shards, tags)
+	perr, ok := err.(ShardPositionError)
+	if !ok {
+		t.Fatalf("expected ShardPositionError, got %v", err)
+	}
+	if len(perr.Positions) != 2 || perr.Positions[0] != 1 || perr.Positions[1] != 4 {
+		t.Fatalf("unexpected positions: %v", perr.Positions)
+	}
+	if perr.Origins[0] != 4 || perr.Origins[1] != 1 {
+		t.Fatalf("unexpected origins: %v", perr.Origins)
+	}
+	if shards[0] != nil {
+		t.Fatal("shards were modified")
+	}
+
+	// Corrupt content should not match any tag.
+	shards[1], shards[4] = shards[4], shards[1]
+	shards[5] = append([]byte{}, shards[5]...)
+	shards[5][0] ^= 1
+	err = r.ReconstructTagged(shards, tags)
+	perr, ok = err.(ShardPositionError)
+	if !ok {
+		t.Fatalf("expected ShardPositionError, got %v", err)
+	}
+	if len(perr.Positions) != 1 || perr.Positions[0] != 5 || perr.Origins[0] != -1 {
+		t.Fatalf("unexpected error: %v", perr)
+	}
+	shards[5][0] ^= 1
+
+	err = r.ReconstructTagged(shards, tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shards[1], shard1) {
+		t.Fatal("shard 1 mismatch")
+	}
+	ok, err = r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("Verification failed")
+	}
+
+	err = r.ReconstructTagged(shards, tags[1:])
+	if err != ErrInvalidTags {
+		t.Errorf("expected %v, got %v", ErrInvalidTags, err)
+	}
+	tags[0], tags[1] = tags[1], tags[0]
+	err = r.ReconstructTagged(shards, tags)
+	if err != ErrInvalidTags {
+		t.Errorf("expected %v, got %v", ErrInvalidTags, err)
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	perShard := 10000
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := make([][]byte, 13)
+	for s := range shards {
+		shards[s] = make([]byte, perShard)
+	}
+
+	rand.Seed(0)
+	for s := 0; s < 10; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shard3 := append([]byte{}, shards[3]...)
+	err = r.AttachChecksums(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range shards {
+		if len(shards[i]) != perShard+ChecksumSize {
+			t.Fatalf("shard %d: unexpected size %d", i, len(shards[i]))
+		}
+	}
+
+	// Flip a bit in two shards, and remove one.
+	shards[3][100] ^= 4
+	shards[12][perShard+1] ^= 1
+	shards[7] = nil
+	bad, err := r.ValidateChecksums(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) != 2 || bad[0] != 3 || bad[1] != 12 {
+		t.Fatalf("unexpected bad shards: %v", bad)
+	}
+	if len(shards[0]) != perShard || len(shards[3]) != perShard+ChecksumSize {
+		t.Fatal("checksums not removed as expected")
+	}
+	for _, i := range bad {
+		shards[i] = nil
+	}
+	err = r.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shards[3], shard3) {
+		t.Fatal("reconstructed shard mismatch")
+	}
+
+	_, err = r.ValidateChecksums(make([][]byte, 13))
+	if err != ErrShardNoData {
+		t.Errorf("expected %v, got %v", ErrShardNoData, err)
+	}
+	// Shards from Split share a backing array.
+	data := make([]byte, 25000)
+	fillRandom(data)
+	shards, _ = r.Split(data)
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.AttachChecksums(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad, err = r.ValidateChecksums(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) != 0 {
+		t.Fatalf("unexpected bad shards: %v", bad)
+	}
+	buf := new(bytes.Buffer)
+	err = r.Join(buf, shards, len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("recovered bytes do not match")
+	}
+
+	err = r.AttachChecksums(make([][]byte, 1))
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+}
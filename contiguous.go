@@ -0,0 +1,114 @@
+package reedsolomon
+
+import (
+	"errors"
+	"io"
+)
+
+// SplitContiguous returns the shards stored back-to-back in buf,
+// each of shardSize bytes, data shards first.
+// The returned shards share memory with buf, and their capacity is
+// limited, so appending to a shard never overwrites the next one.
+//
+// The length of buf must be exactly shardSize times the number of
+// shards, otherwise ErrShardSize is returned.
+func (r reedSolomon) SplitContiguous(buf []byte, shardSize int) ([][]byte, error) {
+	if shardSize <= 0 {
+		return nil, ErrShardNoData
+	}
+	if len(buf) != shardSize*r.Shards {
+		return nil, ErrShardSize
+	}
+	shards := make([][]byte, r.Shards)
+	for i := range shards {
+		shards[i] = buf[i*shardSize : (i+1)*shardSize : (i+1)*shardSize]
+	}
+	return shards, nil
+}
+
+// EncodeContiguous functions as Encode, but takes all shards
+// stored back-to-back in buf, as described for SplitContiguous.
+// The parity part of buf is overwritten in place.
+func (r reedSolomon) EncodeContiguous(buf []byte, shardSize int) error {
+	shards, err := r.SplitContiguous(buf, shardSize)
+	if err != nil {
+		return err
+	}
+	r.mixData(shards[:r.DataShards], shardSize)
+	r.codeSomeShards(r.parity, shards[:r.DataShards], shards[r.DataShards:], r.ParityShards, shardSize)
+	return nil
+}
+
+// ReconstructContiguous functions as Reconstruct, but takes all
+// shards stored back-to-back in buf, as described for SplitContiguous.
+// The shards listed in missing are recreated in place, so their
+// content in buf is ignored and overwritten.
+func (r reedSolomon) ReconstructContiguous(buf []byte, shardSize int, missing []int) error {
+	shards, err := r.SplitContiguous(buf, shardSize)
+	if err != nil {
+		return err
+	}
+	for _, idx := range missing {
+		if idx < 0 || idx >= r.Shards {
+			return ErrInvalidShardIndex
+		}
+		// Keep the capacity, so the shard is recreated in buf.
+		shards[idx] = shards[idx][:0]
+	}
+	r.o.reuseShards = true
+	return r.reconstruct(shards, false)
+}
+
+// ErrInvalidStride is returned by EncodeStrided if the stride is
+// smaller than the shard size, which would make shards overlap.
+var ErrInvalidStride = errors.New("stride is smaller than the shard size")
+
+// EncodeStrided functions as Encode, but takes all shards stored in
+// buf at a fixed stride, data shards first.
+// Shard i is stored in buf[i*stride : i*stride+shardSize], and the bytes
+// between the end of a shard and the start of the next are not used.
+// The parity shards are overwritten in place.
+//
+// The stride must be at least shardSize, otherwise ErrInvalidStride is
+// returned. The length of buf must be at least
+// (Shards-1)*stride + shardSize, otherwise ErrShardSize is returned.
+func (r reedSolomon) EncodeStrided(buf []byte, shardSize, stride int) error {
+	if shardSize <= 0 {
+		return ErrShardNoData
+	}
+	if stride < shardSize {
+		return ErrInvalidStride
+	}
+	if len(buf) < (r.Shards-1)*stride+shardSize {
+		return ErrShardSize
+	}
+	shards := make([][]byte, r.Shards)
+	for i := range shards {
+		off := i * stride
+		shards[i] = buf[off : off+shardSize : off+shardSize]
+	}
+	r.mixData(shards[:r.DataShards], shardSize)
+	r.codeSomeShards(r.parity, shards[:r.DataShards], shards[r.DataShards:], r.ParityShards, shardSize)
+	return nil
+}
+
+// JoinContiguous writes the first outSize bytes of the data shards
+// stored in buf to dst, as described for SplitContiguous.
+// If the data shards contain less than outSize bytes, ErrShortData
+// will be returned, and a negative outSize returns ErrInvalidSize.
+func (r reedSolomon) JoinContiguous(dst io.Writer, buf []byte, shardSize int, outSize int) error {
+	if shardSize <= 0 {
+		return ErrShardNoData
+	}
+	if len(buf) != shardSize*r.Shards {
+		return ErrShardSize
+	}
+	if outSize < 0 {
+		return ErrInvalidSize
+	}
+	if outSize > shardSize*r.DataShards {
+		return ErrShortData
+	}
+	_, err := dst.Write(buf[:outSize])
+	return err
+}
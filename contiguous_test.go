@@ -0,0 +1,144 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestContiguous(t *testing.T) {
+	const shardSize = 1000
+	r, err := newExt(5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 8*shardSize)
+	fillRandom(buf[:5*shardSize])
+	data := append([]byte{}, buf[:5*shardSize-17]...)
+
+	err = r.EncodeContiguous(buf, shardSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards, err := r.SplitContiguous(buf, shardSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("verification failed")
+	}
+	if cap(shards[0]) != shardSize {
+		t.Error("shard capacity not limited")
+	}
+
+	want := append([]byte{}, buf...)
+	for _, i := range []int{0, 4, 6} {
+		fillRandom(buf[i*shardSize : (i+1)*shardSize])
+	}
+	err = r.ReconstructContiguous(buf, shardSize, []int{0, 4, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, want) {
+		t.Fatal("reconstructed buffer mismatch")
+	}
+	// Only the slice of shards is allocated, as the missing shard is
+	// recreated directly in buf.
+	missing := []int{2}
+	allocs := testing.AllocsPerRun(10, func() {
+		err = r.ReconstructContiguous(buf, shardSize, missing)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allocs != 1 {
+		t.Errorf("expected 1 allocation, got %v", allocs)
+	}
+	if !bytes.Equal(buf, want) {
+		t.Fatal("reconstructed buffer mismatch")
+	}
+
+	var out bytes.Buffer
+	err = r.JoinContiguous(&out, buf, shardSize, len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatal("joined data mismatch")
+	}
+
+	err = r.EncodeContiguous(buf[:len(buf)-1], shardSize)
+	if err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+	err = r.EncodeContiguous(buf, 0)
+	if err != ErrShardNoData {
+		t.Errorf("expected %v, got %v", ErrShardNoData, err)
+	}
+	err = r.ReconstructContiguous(buf, shardSize, []int{8})
+	if err != ErrInvalidShardIndex {
+		t.Errorf("expected %v, got %v", ErrInvalidShardIndex, err)
+	}
+	err = r.ReconstructContiguous(buf, shardSize, []int{0, 1, 2, 3})
+	if !errors.Is(err, ErrTooManyFailures) {
+		t.Errorf("expected %v, got %v", ErrTooManyFailures, err)
+	}
+	err = r.JoinContiguous(&out, buf, shardSize, 5*shardSize+1)
+	if err != ErrShortData {
+		t.Errorf("expected %v, got %v", ErrShortData, err)
+	}
+	err = r.JoinContiguous(&out, buf, shardSize, -1)
+	if err != ErrInvalidSize {
+		t.Errorf("expected %v, got %v", ErrInvalidSize, err)
+	}
+}
+
+func TestEncodeStrided(t *testing.T) {
+	const shardSize, stride = 100, 128
+	r, err := newExt(5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 7*stride+shardSize)
+	fillRandom(buf)
+	gaps := append([]byte{}, buf...)
+
+	err = r.EncodeStrided(buf, shardSize, stride)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := make([][]byte, 8)
+	for i := range shards {
+		shards[i] = buf[i*stride : i*stride+shardSize]
+	}
+	ok, err := r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("verification failed")
+	}
+	for i := 0; i < 7; i++ {
+		gap := buf[i*stride+shardSize : (i+1)*stride]
+		if !bytes.Equal(gap, gaps[i*stride+shardSize:(i+1)*stride]) {
+			t.Fatalf("bytes after shard %d were modified", i)
+		}
+	}
+
+	err = r.EncodeStrided(buf, shardSize, shardSize-1)
+	if err != ErrInvalidStride {
+		t.Errorf("expected %v, got %v", ErrInvalidStride, err)
+	}
+	err = r.EncodeStrided(buf[:len(buf)-1], shardSize, stride)
+	if err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+	err = r.EncodeStrided(buf, 0, stride)
+	if err != ErrShardNoData {
+		t.Errorf("expected %v, got %v", ErrShardNoData, err)
+	}
+}
@@ -0,0 +1,291 @@
+package reedsolomon
+
+import "errors"
+
+// ErrCorrectFullField is returned by Correct if the present shards use
+// every element of the Galois field as evaluation point, which leaves
+// no point to shift them by, so the errors cannot be located.
+// This can only happen if no shards are missing, and there are 256
+// shards, or 16 with WithNibbleField.
+var ErrCorrectFullField = errors.New("cannot correct shards using every element of the field")
+
+// Correct locates and repairs shards that are silently corrupted, and
+// returns the indexes of the shards it repaired, in increasing order.
+// Missing (nil) shards are recreated as well, but are not returned.
+//
+// Every shard set produced by the encoder is a Reed-Solomon codeword,
+// byte by byte, so the errors of every byte are located from its
+// syndromes with the Berlekamp-Massey algorithm. Up to
+// (ParityShards-missing)/2 corrupted shards can be located for a byte,
+// where missing is the number of nil shards, and different bytes may
+// be corrupted in different shards. The shards found are then
+// recreated as if they were missing, so in total up to
+// ParityShards-missing shards can be repaired.
+// More corrupted shards per byte can go undetected or be "repaired"
+// to another consistent set, so Correct should not replace checksums
+// when they are available.
+//
+// The syndromes cost about as much as Encode, and every byte with
+// errors is decoded separately, which is slower.
+//
+// If the shards are inconsistent, but the errors cannot be located,
+// ErrCorruptionNotLocated is returned and the shards are not modified.
+// Repaired shards are overwritten in place.
+func (r reedSolomon) Correct(shards [][]byte) ([]int, error) {
+	if len(shards) != r.Shards {
+		return nil, ErrShardCount
+	}
+	err := checkShards(shards, true)
+	if err != nil {
+		return nil, err
+	}
+	var present []int
+	for i, shard := range shards {
+		if len(shard) != 0 {
+			present = append(present, i)
+		}
+	}
+	if len(present) < r.DataShards {
+		return nil, tooManyFailures(shards, r.DataShards)
+	}
+
+	located := make([]bool, len(present))
+	if checks := len(present) - r.DataShards; checks > 0 {
+		h, x, err := r.checkMatrix(present)
+		if err != nil {
+			return nil, err
+		}
+		size := shardSize(shards)
+		synd := AllocAligned(checks, size)
+		for j, row := range h {
+			for k, idx := range present {
+				if k == 0 {
+					galMulSlice(row[k], shards[idx], synd[j], &r.o)
+				} else {
+					galMulSliceXor(row[k], shards[idx], synd[j], &r.o)
+				}
+			}
+		}
+
+		loc := newErrorLocator(r.o.field, checks)
+		s := make([]byte, checks)
+		low, high := make([]byte, checks), make([]byte, checks)
+		for b := 0; b < size; b++ {
+			var nonzero byte
+			for j := range synd {
+				s[j] = synd[j][b]
+				nonzero |= s[j]
+			}
+			if nonzero == 0 {
+				continue
+			}
+			if r.o.nibbleField {
+				// The two halves are independent symbols.
+				for j, v := range s {
+					low[j], high[j] = v&15, v>>4
+				}
+				if !loc.locate(low, x, located) || !loc.locate(high, x, located) {
+					return nil, ErrCorruptionNotLocated
+				}
+			} else if !loc.locate(s, x, located) {
+				return nil, ErrCorruptionNotLocated
+			}
+		}
+	}
+
+	var corrected []int
+	for k, bad := range located {
+		if bad {
+			corrected = append(corrected, present[k])
+		}
+	}
+	if len(corrected) > len(present)-r.DataShards {
+		return nil, ErrCorruptionNotLocated
+	}
+	if len(corrected) == 0 && len(present) == r.Shards {
+		return nil, nil
+	}
+
+	// Recreate the corrupted shards from the others.
+	// This reconstruction is not reported to WithReconstructPlan.
+	r.o.reconstructPlan = nil
+	r.o.verifyReconstruct = false
+	test := make([][]byte, r.Shards)
+	copy(test, shards)
+	for _, idx := range corrected {
+		test[idx] = nil
+	}
+	err = r.reconstruct(test, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range corrected {
+		copy(shards[idx], test[idx])
+	}
+	for i := range shards {
+		if len(shards[i]) == 0 {
+			shards[i] = test[i]
+		}
+	}
+	return corrected, nil
+}
+
+// evaluationPoints returns the encoder as a generalized Reed-Solomon
+// code: for every byte, shard i of an encoded set holds
+// v[i]*f(alpha[i]), for a polynomial f of degree below DataShards.
+//
+// The Vandermonde matrix evaluates f at the point i, with v[i] = 1.
+// A Cauchy matrix with entries 1/(x[i]-y[c]) gives
+// sum(a[c]/(x[i]-y[c])) = f(x[i])/L(x[i]), where L is the product of
+// (x-y[c]) over all columns, so v[i] = 1/L(x[i]). The systematic
+// Cauchy matrix has data shard c at point y[c] = c, where the identity
+// row gives v[c] = 1/L'(c), the product of (c-y[l]) for l != c.
+func (r reedSolomon) evaluationPoints() (alpha, v []byte) {
+	f := r.o.field
+	alpha = make([]byte, r.Shards)
+	v = make([]byte, r.Shards)
+	for i := range alpha {
+		alpha[i] = byte(i)
+		v[i] = 1
+		if r.o.nonSystematic {
+			alpha[i] = byte(r.DataShards + i)
+		} else if !r.o.useCauchy {
+			continue
+		}
+		prod := byte(1)
+		for c := 0; c < r.DataShards; c++ {
+			if r.o.nonSystematic || i != c {
+				prod = f.multiply(prod, alpha[i]^byte(c))
+			}
+		}
+		v[i] = f.divide(1, prod)
+	}
+	return alpha, v
+}
+
+// checkMatrix returns the parity check matrix of the shards at the
+// indexes in present, with one row per check and one column per
+// present shard, so every row multiplied with a consistent set of
+// shards is zero. Row j holds u[k]*x[k]^j, where x[k] are the
+// returned points, so the rows give the syndromes used by
+// errorLocator.
+//
+// The present shards form a shorter Reed-Solomon code, with the same
+// points and multipliers as the complete set. The dual of this code
+// has the multipliers u[k] = 1/(v[k] * product of (a[k]-a[l]), l != k).
+// The points are shifted by a value that is not a point, so none
+// of them is zero, which would make the error undetectable by the
+// error locator. The code with shifted points and the same multipliers
+// is the same, as f(x+beta) also has a degree below DataShards.
+func (r reedSolomon) checkMatrix(present []int) (matrix, []byte, error) {
+	f := r.o.field
+	alpha, v := r.evaluationPoints()
+	used := make([]bool, f.size())
+	for _, idx := range present {
+		used[alpha[idx]] = true
+	}
+	beta := -1
+	for c, u := range used {
+		if !u {
+			beta = c
+			break
+		}
+	}
+	if beta < 0 {
+		return nil, nil, ErrCorrectFullField
+	}
+
+	x := make([]byte, len(present))
+	h, _ := newMatrix(len(present)-r.DataShards, len(present))
+	for k, idx := range present {
+		prod := v[idx]
+		for _, l := range present {
+			if l != idx {
+				prod = f.multiply(prod, alpha[idx]^alpha[l])
+			}
+		}
+		u := f.divide(1, prod)
+		x[k] = alpha[idx] ^ byte(beta)
+		for j := range h {
+			h[j][k] = f.multiply(u, f.exp(x[k], j))
+		}
+	}
+	return h, x, nil
+}
+
+// errorLocator finds the positions of errors from syndromes with the
+// Berlekamp-Massey algorithm. It holds the buffers, so it can be
+// reused for every byte.
+type errorLocator struct {
+	f          *galField
+	c, b, prev []byte
+}
+
+func newErrorLocator(f *galField, checks int) *errorLocator {
+	return &errorLocator{
+		f:    f,
+		c:    make([]byte, checks+1),
+		b:    make([]byte, checks+1),
+		prev: make([]byte, checks+1),
+	}
+}
+
+// locate marks the errors described by the syndromes s in located,
+// where s[j] is the sum of e[k]*x[k]^j over the errors e[k].
+// The error locator polynomial, with a root at 1/x[k] for every error,
+// is found with the Berlekamp-Massey algorithm, and the roots are found
+// by trying every point.
+// It returns false if there are more than len(s)/2 errors, and they
+// cannot be located.
+func (e *errorLocator) locate(s, x []byte, located []bool) bool {
+	f, c, b := e.f, e.c, e.b
+	for i := range c {
+		c[i], b[i] = 0, 0
+	}
+	c[0], b[0] = 1, 1
+	n, m, last := 0, 1, byte(1)
+	for i := range s {
+		d := s[i]
+		for j := 1; j <= n; j++ {
+			d ^= f.multiply(c[j], s[i-j])
+		}
+		if d == 0 {
+			m++
+			continue
+		}
+		coef := f.divide(d, last)
+		grow := 2*n <= i
+		if grow {
+			copy(e.prev, c)
+		}
+		for j := 0; j+m < len(c); j++ {
+			c[j+m] ^= f.multiply(coef, b[j])
+		}
+		if grow {
+			n = i + 1 - n
+			copy(b, e.prev)
+			last = d
+			m = 1
+		} else {
+			m++
+		}
+	}
+	if 2*n > len(s) {
+		return false
+	}
+
+	found := 0
+	for k, xk := range x {
+		inv := f.divide(1, xk)
+		sum, p := byte(0), byte(1)
+		for j := 0; j <= n; j++ {
+			sum ^= f.multiply(c[j], p)
+			p = f.multiply(p, inv)
+		}
+		if sum == 0 {
+			located[k] = true
+			found++
+		}
+	}
+	return found == n
+}
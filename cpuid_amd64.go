@@ -0,0 +1,21 @@
+//+build !noasm
+//+build !appengine
+//+build !rs_pure
+//+build !rs_avx2
+
+// Copyright 2015, Klaus Post, see LICENSE for details.
+
+package reedsolomon
+
+import (
+	"github.com/klauspost/cpuid"
+)
+
+// avx2Only is true if only the AVX2 assembly is used.
+const avx2Only = false
+
+func init() {
+	// Detect CPU capabilities.
+	defaultOptions.useSSSE3 = cpuid.CPU.SSSE3()
+	defaultOptions.useAVX2 = cpuid.CPU.AVX2()
+}
@@ -0,0 +1,19 @@
+//+build !noasm
+//+build !appengine
+//+build !rs_pure
+//+build rs_avx2
+
+// Copyright 2015, Klaus Post, see LICENSE for details.
+
+package reedsolomon
+
+// avx2Only is true if only the AVX2 assembly is used.
+// The SSSE3 functions are then never referenced, so they are
+// left out of the binary.
+const avx2Only = true
+
+func init() {
+	// The CPU is not detected, so the binary will only run on CPUs
+	// supporting AVX2.
+	defaultOptions.useAVX2 = true
+}
@@ -10,22 +10,21 @@
 //
 // go build simple-decoder.go
 //
-// Simple Encoder/Decoder Shortcomings:
-// * If the file size of the input isn't diviable by the number of data shards
-//   the output will contain extra zeroes
+// The file size is stored in a header in the first data shard,
+// so the output will have the exact size of the input.
 //
-// * If the shard numbers isn't the same for the decoder as in the
-//   encoder, invalid output will be generated.
+// The number of data/parity shards is stored in a manifest file,
+// basefile.ext.manifest, so the decoder always uses the same
+// configuration as the encoder.
 //
+// Simple Encoder/Decoder Shortcomings:
 // * If values have changed in a shard, it cannot be reconstructed.
 //
 // * If two shards have been swapped, reconstruction will always fail.
 //   You need to supply the shards in the same order as they were given to you.
 //
-// The solution for this is to save a metadata file containing:
+// The solution for this is to also save in the metadata:
 //
-// * File size.
-// * The number of data/parity shards.
 // * HASH of each shard.
 // * Order of the shards.
 //
@@ -35,6 +34,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -43,8 +43,6 @@ import (
 	"github.com/klauspost/reedsolomon"
 )
 
-var dataShards = flag.Int("data", 4, "Number of shards to split the data into")
-var parShards = flag.Int("par", 2, "Number of parity shards")
 var outFile = flag.String("out", "", "Alternative output path/file")
 
 func init() {
@@ -67,12 +65,19 @@ func main() {
 	}
 	fname := args[0]
 
+	// Read the manifest written by the encoder.
+	mb, err := ioutil.ReadFile(fname + ".manifest")
+	checkErr(err)
+	var m reedsolomon.Manifest
+	err = json.Unmarshal(mb, &m)
+	checkErr(err)
+
 	// Create matrix
-	enc, err := reedsolomon.New(*dataShards, *parShards)
+	enc, err := reedsolomon.NewFromManifest(m)
 	checkErr(err)
 
 	// Create shards and load the data.
-	shards := make([][]byte, *dataShards+*parShards)
+	shards := make([][]byte, m.DataShards+m.ParityShards)
 	for i := range shards {
 		infn := fmt.Sprintf("%s.%d", fname, i)
 		fmt.Println("Opening", infn)
@@ -112,8 +117,8 @@ func main() {
 	f, err := os.Create(outfn)
 	checkErr(err)
 
-	// We don't know the exact filesize.
-	err = enc.Join(f, shards, len(shards[0])**dataShards)
+	// The exact file size is read from the header.
+	err = enc.(reedsolomon.Extensions).JoinWithHeader(f, shards)
 	checkErr(err)
 }
 
@@ -11,22 +11,21 @@
 //
 // go build simple-decoder.go
 //
-// Simple Encoder/Decoder Shortcomings:
-// * If the file size of the input isn't diviable by the number of data shards
-//   the output will contain extra zeroes
+// The file size is stored in a header in the first data shard,
+// so the output will have the exact size of the input.
 //
-// * If the shard numbers isn't the same for the decoder as in the
-//   encoder, invalid output will be generated.
+// The number of data/parity shards is stored in a manifest file,
+// basefile.ext.manifest, so the decoder always uses the same
+// configuration as the encoder.
 //
+// Simple Encoder/Decoder Shortcomings:
 // * If values have changed in a shard, it cannot be reconstructed.
 //
 // * If two shards have been swapped, reconstruction will always fail.
 //   You need to supply the shards in the same order as they were given to you.
 //
-// The solution for this is to save a metadata file containing:
+// The solution for this is to also save in the metadata:
 //
-// * File size.
-// * The number of data/parity shards.
 // * HASH of each shard.
 // * Order of the shards.
 //
@@ -36,6 +35,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -81,15 +81,12 @@ func main() {
 	b, err := ioutil.ReadFile(fname)
 	checkErr(err)
 
-	// Split the file into equally sized shards.
-	shards, err := enc.Split(b)
+	// Split the file into equally sized shards, with a size header,
+	// and encode parity.
+	shards, err := enc.(reedsolomon.Extensions).EncodeWithHeader(b)
 	checkErr(err)
 	fmt.Printf("File split into %d data+parity shards with %d bytes/shard.\n", len(shards), len(shards[0]))
 
-	// Encode parity
-	err = enc.Encode(shards)
-	checkErr(err)
-
 	// Write out the resulting files.
 	dir, file := filepath.Split(fname)
 	if *outDir != "" {
@@ -102,6 +99,20 @@ func main() {
 		err = ioutil.WriteFile(filepath.Join(dir, outfn), shard, os.ModePerm)
 		checkErr(err)
 	}
+
+	// Write the manifest, so the decoder can recreate the encoder.
+	m := reedsolomon.Manifest{
+		Size:         int64(len(b)),
+		ShardSize:    len(shards[0]),
+		DataShards:   *dataShards,
+		ParityShards: *parShards,
+	}
+	mb, err := json.Marshal(m)
+	checkErr(err)
+	outfn := file + ".manifest"
+	fmt.Println("Writing to", outfn)
+	err = ioutil.WriteFile(filepath.Join(dir, outfn), mb, os.ModePerm)
+	checkErr(err)
 }
 
 func checkErr(err error) {
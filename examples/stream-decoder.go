@@ -39,7 +39,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 
 	"github.com/klauspost/reedsolomon"
 )
@@ -76,6 +75,21 @@ func main() {
 	shards, size, err := openInput(*dataShards, *parShards, fname)
 	checkErr(err)
 
+	// Output file name
+	outfn := *outFile
+	if outfn == "" {
+		outfn = fname
+	}
+
+	if size == 0 {
+		// Shards are only empty if the input file was empty.
+		fmt.Println("All shards are empty, writing empty file to", outfn)
+		f, err := os.Create(outfn)
+		checkErr(err)
+		checkErr(f.Close())
+		return
+	}
+
 	// Verify the shards
 	ok, err := enc.Verify(shards)
 	if ok {
@@ -88,10 +102,9 @@ func main() {
 		out := make([]io.Writer, len(shards))
 		for i := range out {
 			if shards[i] == nil {
-				dir, _ := filepath.Split(fname)
 				outfn := fmt.Sprintf("%s.%d", fname, i)
 				fmt.Println("Creating", outfn)
-				out[i], err = os.Create(filepath.Join(dir, outfn))
+				out[i], err = os.Create(outfn)
 				checkErr(err)
 			}
 		}
@@ -117,11 +130,6 @@ func main() {
 	}
 
 	// Join the shards and write them
-	outfn := *outFile
-	if outfn == "" {
-		outfn = fname
-	}
-
 	fmt.Println("Writing data to", outfn)
 	f, err := os.Create(outfn)
 	checkErr(err)
@@ -137,6 +145,7 @@ func main() {
 func openInput(dataShards, parShards int, fname string) (r []io.Reader, size int64, err error) {
 	// Create shards and load the data.
 	shards := make([]io.Reader, dataShards+parShards)
+	opened := 0
 	for i := range shards {
 		infn := fmt.Sprintf("%s.%d", fname, i)
 		fmt.Println("Opening", infn)
@@ -147,6 +156,7 @@ func openInput(dataShards, parShards int, fname string) (r []io.Reader, size int
 			continue
 		} else {
 			shards[i] = f
+			opened++
 		}
 		stat, err := f.Stat()
 		checkErr(err)
@@ -156,6 +166,9 @@ func openInput(dataShards, parShards int, fname string) (r []io.Reader, size int
 			shards[i] = nil
 		}
 	}
+	if opened < dataShards {
+		return nil, 0, reedsolomon.ErrTooFewShards
+	}
 	return shards, size, nil
 }
 
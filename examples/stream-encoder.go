@@ -100,6 +100,15 @@ func main() {
 		checkErr(err)
 	}
 
+	if instat.Size() == 0 {
+		// Nothing to split, so all shards are empty.
+		for i := range out {
+			checkErr(out[i].Close())
+		}
+		fmt.Printf("Empty file written as %d empty shards.\n", shards)
+		return
+	}
+
 	// Split into files.
 	data := make([]io.Writer, *dataShards)
 	for i := range data {
@@ -0,0 +1,67 @@
+//+build go1.18
+
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+// FuzzRoundTrip checks that Split, Encode, Reconstruct and Join return
+// the original data, for any data size and any recoverable failure.
+// The corpus in testdata/fuzz/FuzzRoundTrip is run by go test.
+func FuzzRoundTrip(f *testing.F) {
+	f.Add([]byte{}, uint8(4), uint8(2), uint64(0))
+	f.Add([]byte{1}, uint8(4), uint8(2), uint64(1))
+	f.Add([]byte("abc"), uint8(10), uint8(3), uint64(0x7))
+	f.Add(make([]byte, 41), uint8(10), uint8(4), uint64(0x2c1))
+	f.Fuzz(func(t *testing.T, data []byte, dataShards, parityShards uint8, failures uint64) {
+		d, p := 1+int(dataShards%32), int(parityShards%8)
+		r, err := New(d, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards, err := r.Split(append([]byte{}, data...))
+		if len(data) == 0 {
+			if err != ErrShortData {
+				t.Fatalf("expected %v, got %v", ErrShortData, err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.Encode(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Remove up to p shards, selected by the bits of failures.
+		removed := 0
+		for i := range shards {
+			if removed < p && failures&(1<<uint(i%64)) != 0 {
+				shards[i] = nil
+				removed++
+			}
+		}
+		err = r.Reconstruct(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok, err := r.Verify(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("verification failed after reconstruction")
+		}
+		var buf bytes.Buffer
+		err = r.Join(&buf, shards, len(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Fatalf("%d+%d shards, %d bytes: joined data mismatch", d, p, len(data))
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package reedsolomon
+
+import "errors"
+
+// ErrInvalidFieldPoly is returned by New if the polynomial given
+// to WithFieldPoly is not a primitive polynomial of degree 8.
+var ErrInvalidFieldPoly = errors.New("field polynomial must be a primitive polynomial of degree 8")
+
+// ErrMaxShardNumNibble is returned by New, if you attempt to create
+// an Encoder using WithNibbleField with more than 16 data+parity shards.
+var ErrMaxShardNumNibble = errors.New("cannot create Encoder with more than 16 data+parity shards in GF(2^4)")
+
+// galField contains the tables for a GF(2^8) field generated by
+// a polynomial other than the default, or for GF(2^4).
+//
+// A nil *galField is the default field, which uses the precomputed
+// package tables, so the methods can be called on a nil pointer.
+type galField struct {
+	logTable     [fieldSize]byte
+	expTable     [fieldSize*2 - 2]byte
+	mulTable     [256][256]uint8
+	mulTableLow  [256][16]uint8
+	mulTableHigh [256][16]uint8
+	order        int // Number of non-zero elements.
+}
+
+// newGalField generates the tables for the field given by poly.
+// poly must include the x^8 term, so 0x11d is the default field.
+// ErrInvalidFieldPoly is returned if x does not generate all
+// non-zero elements of the field.
+func newGalField(poly int) (*galField, error) {
+	if poly < 0x100 || poly > 0x1ff {
+		return nil, ErrInvalidFieldPoly
+	}
+	f := &galField{order: fieldSize - 1}
+	x := 1
+	for i := 0; i < fieldSize-1; i++ {
+		if i > 0 && x == 1 {
+			// Order of x is less than 255.
+			return nil, ErrInvalidFieldPoly
+		}
+		f.expTable[i] = byte(x)
+		f.expTable[i+fieldSize-1] = byte(x)
+		f.logTable[x] = byte(i)
+		x <<= 1
+		if x >= fieldSize {
+			x ^= poly
+		}
+	}
+	if x != 1 {
+		return nil, ErrInvalidFieldPoly
+	}
+
+	for a := 1; a < fieldSize; a++ {
+		for b := 1; b < fieldSize; b++ {
+			f.mulTable[a][b] = f.expTable[int(f.logTable[a])+int(f.logTable[b])]
+		}
+	}
+	for c := range f.mulTable {
+		for i := 0; i < 16; i++ {
+			f.mulTableLow[c][i] = f.mulTable[c][i]
+			f.mulTableHigh[c][i] = f.mulTable[c][i<<4]
+		}
+	}
+	return f, nil
+}
+
+// nibbleFieldPoly is the polynomial generating GF(2^4), x^4 + x + 1.
+const nibbleFieldPoly = 0x13
+
+// newNibbleField generates the tables for GF(2^4), where every byte
+// holds two symbols.
+// multiply, divide and exp work on single symbols from 0 to 15, while
+// the tables returned by tables multiply both symbols of a byte, so
+// the galMulSlice functions can be used unchanged.
+func newNibbleField() *galField {
+	f := &galField{order: 15}
+	x := 1
+	for i := 0; i < f.order; i++ {
+		f.expTable[i] = byte(x)
+		f.expTable[i+f.order] = byte(x)
+		f.logTable[x] = byte(i)
+		x <<= 1
+		if x >= 16 {
+			x ^= nibbleFieldPoly
+		}
+	}
+	mul := func(a, b int) byte {
+		if a == 0 || b == 0 {
+			return 0
+		}
+		return f.expTable[int(f.logTable[a])+int(f.logTable[b])]
+	}
+	for c := 0; c < 16; c++ {
+		for b := 0; b < 256; b++ {
+			f.mulTable[c][b] = mul(c, b&15) | mul(c, b>>4)<<4
+		}
+		for i := 0; i < 16; i++ {
+			f.mulTableLow[c][i] = f.mulTable[c][i]
+			f.mulTableHigh[c][i] = f.mulTable[c][i<<4]
+		}
+	}
+	return f
+}
+
+// size returns the number of elements of the field, which is the
+// maximum number of shards.
+func (f *galField) size() int {
+	if f == nil {
+		return fieldSize
+	}
+	return f.order + 1
+}
+
+// tables returns the multiplication tables for c, as used by
+// the galMulSlice functions.
+func (f *galField) tables(c byte) (low, high *[16]uint8, mt *[256]uint8) {
+	if f == nil {
+		return &mulTableLow[c], &mulTableHigh[c], &mulTable[c]
+	}
+	return &f.mulTableLow[c], &f.mulTableHigh[c], &f.mulTable[c]
+}
+
+// multiply multiplies two elements of the field.
+func (f *galField) multiply(a, b byte) byte {
+	if f == nil {
+		return galMultiply(a, b)
+	}
+	return f.mulTable[a][b]
+}
+
+// divide is inverse of multiply.
+func (f *galField) divide(a, b byte) byte {
+	if f == nil {
+		return galDivide(a, b)
+	}
+	if a == 0 {
+		return 0
+	}
+	if b == 0 {
+		panic("Argument 'divisor' is 0")
+	}
+	logResult := int(f.logTable[a]) - int(f.logTable[b])
+	if logResult < 0 {
+		logResult += f.order
+	}
+	return f.expTable[logResult]
+}
+
+// exp computes a**n.
+func (f *galField) exp(a byte, n int) byte {
+	if f == nil {
+		return galExp(a, n)
+	}
+	if n == 0 {
+		return 1
+	}
+	if a == 0 {
+		return 0
+	}
+	n %= f.order
+	if n < 0 {
+		n += f.order
+	}
+	return f.expTable[(int(f.logTable[a])*n)%f.order]
+}
@@ -0,0 +1,264 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// refMultiply is a reference carry-less multiplication
+// in the field given by poly.
+func refMultiply(a, b byte, poly int) byte {
+	var res int
+	x, y := int(a), int(b)
+	for y > 0 {
+		if y&1 != 0 {
+			res ^= x
+		}
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= poly
+		}
+		y >>= 1
+	}
+	return byte(res)
+}
+
+func TestGalField(t *testing.T) {
+	// The default polynomial must reproduce the package tables.
+	f, err := newGalField(0x11d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.mulTable != mulTable || f.mulTableLow != mulTableLow || f.mulTableHigh != mulTableHigh {
+		t.Fatal("tables for 0x11d do not match the default tables")
+	}
+
+	for _, poly := range []int{0x12b, 0x14d, 0x1f5} {
+		f, err := newGalField(poly)
+		if err != nil {
+			t.Fatalf("poly %#x: %v", poly, err)
+		}
+		for a := 0; a < 256; a++ {
+			for b := 0; b < 256; b++ {
+				want := refMultiply(byte(a), byte(b), poly)
+				if got := f.multiply(byte(a), byte(b)); got != want {
+					t.Fatalf("poly %#x: %d*%d: got %d, want %d", poly, a, b, got, want)
+				}
+				if b != 0 {
+					if got := f.divide(want, byte(b)); a != 0 && got != byte(a) {
+						t.Fatalf("poly %#x: %d/%d: got %d, want %d", poly, want, b, got, a)
+					}
+				}
+			}
+			if got, want := f.exp(byte(a), 3), refMultiply(refMultiply(byte(a), byte(a), poly), byte(a), poly); got != want {
+				t.Fatalf("poly %#x: %d**3: got %d, want %d", poly, a, got, want)
+			}
+			if a != 0 && (f.exp(byte(a), math.MaxInt/255*255) != 1 || f.multiply(f.exp(byte(a), -7), f.exp(byte(a), 7)) != 1) {
+				t.Fatalf("poly %#x: %d**n does not cycle with order 255", poly, a)
+			}
+		}
+	}
+
+	// 0x11b is irreducible, but x only has order 51.
+	for _, poly := range []int{0, 0x1d, 0x11b, 0x100, 0x1ff, 0x200} {
+		_, err := newGalField(poly)
+		if err != ErrInvalidFieldPoly {
+			t.Errorf("poly %#x: expected %v, got %v", poly, ErrInvalidFieldPoly, err)
+		}
+	}
+}
+
+func TestFieldPoly(t *testing.T) {
+	_, err := New(10, 3, WithFieldPoly(0x11b))
+	if err != ErrInvalidFieldPoly {
+		t.Errorf("expected %v, got %v", ErrInvalidFieldPoly, err)
+	}
+
+	data := make([]byte, 10000)
+	fillRandom(data)
+
+	// Explicitly selecting the default field gives the default output.
+	r, err := New(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, _ := r.Split(append([]byte{}, data...))
+	r.Encode(def)
+	r, err = New(10, 3, WithFieldPoly(0x11d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, _ := r.Split(append([]byte{}, data...))
+	r.Encode(same)
+	for i := range def {
+		if !bytes.Equal(def[i], same[i]) {
+			t.Fatal("shard mismatch for 0x11d at", i)
+		}
+	}
+
+	for _, cauchy := range []bool{false, true} {
+		for _, o := range backendOptions() {
+			opts := []Option{WithFieldPoly(0x12b)}
+			if cauchy {
+				opts = append(opts, WithCauchyMatrix())
+			}
+			enc, err := New(10, 3, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := enc.(*reedSolomon)
+			r.o.useAVX2, r.o.useSSSE3, r.o.useNEON = o.useAVX2, o.useSSSE3, o.useNEON
+			shards, _ := r.Split(append([]byte{}, data...))
+			err = r.Encode(shards)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Check the parity with reference arithmetic.
+			m := r.Matrix()
+			for p, row := range m {
+				want := make([]byte, len(shards[0]))
+				for c, coeff := range row {
+					for i, v := range shards[c] {
+						want[i] ^= refMultiply(coeff, v, 0x12b)
+					}
+				}
+				if !bytes.Equal(want, shards[10+p]) {
+					t.Fatalf("%s: parity %d does not match reference", r.Backend(), p)
+				}
+			}
+			if !cauchy && bytes.Equal(shards[10], def[10]) {
+				t.Fatal("custom field gave default output")
+			}
+
+			shards[1], shards[4], shards[12] = nil, nil, nil
+			err = r.Reconstruct(shards)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ok, err := r.Verify(shards)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Fatal("verification failed")
+			}
+			if !bytes.Equal(shards[1], def[1]) || !bytes.Equal(shards[4], def[4]) {
+				t.Fatal("reconstructed data mismatch")
+			}
+		}
+	}
+}
+
+// refMultiply4 is a reference multiplication in GF(2^4).
+func refMultiply4(a, b byte) byte {
+	var res byte
+	for b > 0 {
+		if b&1 != 0 {
+			res ^= a
+		}
+		a <<= 1
+		if a&0x10 != 0 {
+			a ^= nibbleFieldPoly
+		}
+		b >>= 1
+	}
+	return res
+}
+
+func TestNibbleField(t *testing.T) {
+	f := newNibbleField()
+	for a := byte(0); a < 16; a++ {
+		for b := byte(0); b < 16; b++ {
+			want := refMultiply4(a, b)
+			if got := f.multiply(a, b); got != want {
+				t.Fatalf("%d*%d: got %d, want %d", a, b, got, want)
+			}
+			if b != 0 {
+				if got := f.divide(want, b); got != a {
+					t.Fatalf("%d/%d: got %d, want %d", want, b, got, a)
+				}
+			}
+		}
+		if got, want := f.exp(a, 3), refMultiply4(refMultiply4(a, a), a); got != want {
+			t.Fatalf("%d**3: got %d, want %d", a, got, want)
+		}
+		if a == 0 {
+			continue
+		}
+		inv := f.divide(1, a)
+		for _, n := range []int{1, 4, 15, math.MaxInt / 3, math.MaxInt, math.MinInt + 1} {
+			if got := f.multiply(f.exp(a, n), f.exp(a, -n)); got != 1 {
+				t.Fatalf("%d**%d * %d**%d: got %d, want 1", a, n, a, -n, got)
+			}
+			if got, want := f.exp(a, -n), f.exp(inv, n); got != want {
+				t.Fatalf("%d**%d: got %d, want %d", a, -n, got, want)
+			}
+		}
+	}
+
+	_, err := New(10, 7, WithNibbleField())
+	if err != ErrMaxShardNumNibble {
+		t.Errorf("expected %v, got %v", ErrMaxShardNumNibble, err)
+	}
+	_, err = New(7, 3, WithNibbleField(), WithNonSystematic())
+	if err != ErrMaxShardNumNibble {
+		t.Errorf("expected %v, got %v", ErrMaxShardNumNibble, err)
+	}
+	_, err = New(10, 3, WithNibbleField(), WithFieldPoly(0x12b))
+	if err != ErrInvalidFieldPoly {
+		t.Errorf("expected %v, got %v", ErrInvalidFieldPoly, err)
+	}
+
+	data := make([]byte, 10001)
+	fillRandom(data)
+	for _, cauchy := range []bool{false, true} {
+		for _, o := range backendOptions() {
+			opts := []Option{WithNibbleField()}
+			if cauchy {
+				opts = append(opts, WithCauchyMatrix())
+			}
+			enc, err := New(12, 4, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := enc.(*reedSolomon)
+			r.o.useAVX2, r.o.useSSSE3, r.o.useNEON = o.useAVX2, o.useSSSE3, o.useNEON
+			shards, _ := r.Split(append([]byte{}, data...))
+			err = r.Encode(shards)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Check both halves of every byte with reference arithmetic.
+			for p, row := range r.Matrix() {
+				want := make([]byte, len(shards[0]))
+				for c, coeff := range row {
+					if coeff > 15 {
+						t.Fatalf("coefficient %d outside GF(2^4)", coeff)
+					}
+					for i, v := range shards[c] {
+						want[i] ^= refMultiply4(coeff, v&15) | refMultiply4(coeff, v>>4)<<4
+					}
+				}
+				if !bytes.Equal(want, shards[12+p]) {
+					t.Fatalf("%s: parity %d does not match reference", r.Backend(), p)
+				}
+			}
+
+			orig := make([][]byte, len(shards))
+			copy(orig, shards)
+			shards[0], shards[5], shards[11], shards[14] = nil, nil, nil, nil
+			err = r.Reconstruct(shards)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range shards {
+				if !bytes.Equal(shards[i], orig[i]) {
+					t.Fatalf("%s: shard %d not reconstructed", r.Backend(), i)
+				}
+			}
+		}
+	}
+}
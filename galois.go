@@ -6,6 +6,8 @@
 
 package reedsolomon
 
+import "errors"
+
 const (
 	// The number of elements in the field.
 	fieldSize = 256
@@ -117,6 +119,7 @@ func galDivide(a, b byte) byte {
 // Computes a**n.
 //
 // The result will be the same as multiplying a times itself n times.
+// A negative n gives the inverse of a**-n, and 0 if a is 0.
 func galExp(a byte, n int) byte {
 	if n == 0 {
 		return 1
@@ -125,10 +128,39 @@ func galExp(a byte, n int) byte {
 		return 0
 	}
 
+	// Every non-zero element has a**255 == 1, so reduce n first,
+	// which also keeps the product below from overflowing.
+	n %= 255
+	if n < 0 {
+		n += 255
+	}
 	logA := logTable[a]
-	logResult := int(logA) * n
-	for logResult >= 255 {
-		logResult -= 255
+	return byte(expTable[(int(logA)*n)%255])
+}
+
+// ErrDivideByZero is returned by GFDiv if the divisor is 0.
+var ErrDivideByZero = errors.New("division by zero in GF(2^8)")
+
+// GFMul multiplies two elements of GF(2^8), using the same field
+// as the default encoder (generating polynomial 0x11d).
+// Addition and subtraction in the field are both XOR.
+func GFMul(a, b byte) byte {
+	return galMultiply(a, b)
+}
+
+// GFDiv divides a by b in GF(2^8), so GFMul(GFDiv(a, b), b) == a.
+// ErrDivideByZero is returned if b is 0.
+func GFDiv(a, b byte) (byte, error) {
+	if b == 0 {
+		return 0, ErrDivideByZero
 	}
-	return byte(expTable[logResult])
+	return galDivide(a, b), nil
+}
+
+// GFExp returns a raised to the power n in GF(2^8).
+// GFExp(a, 0) is 1 for all a. A negative n gives the inverse of
+// GFExp(a, -n), so GFMul(GFExp(a, n), GFExp(a, -n)) == 1 for a != 0,
+// while GFExp(0, n) is 0 for every n other than 0.
+func GFExp(a byte, n int) byte {
+	return galExp(a, n)
 }
@@ -0,0 +1,129 @@
+/**
+ * 16-bit Galois Field
+ */
+
+package reedsolomon
+
+import (
+	"sync"
+)
+
+const (
+	// The number of elements in the field.
+	fieldSize16 = 1 << 16
+
+	// The polynomial used to generate the logarithm table.
+	// x^16 + x^5 + x^3 + x^2 + 1
+	generatingPolynomial16 = 0x1002d
+)
+
+var (
+	gf16Once sync.Once
+
+	// logTable16[x] is the logarithm of x, for x > 0.
+	logTable16 *[fieldSize16]uint16
+
+	// expTable16 is twice the field size, so the sum
+	// of two logarithms can be looked up directly.
+	expTable16 *[fieldSize16 * 2]uint16
+)
+
+// initGF16 generates the log and exp tables.
+// The tables take up 384KB, so they are only generated
+// when a GF(2^16) encoder is created.
+func initGF16() {
+	gf16Once.Do(func() {
+		logs := new([fieldSize16]uint16)
+		exps := new([fieldSize16 * 2]uint16)
+		x := 1
+		for i := 0; i < fieldSize16-1; i++ {
+			exps[i] = uint16(x)
+			exps[i+fieldSize16-1] = uint16(x)
+			logs[x] = uint16(i)
+			x <<= 1
+			if x >= fieldSize16 {
+				x ^= generatingPolynomial16
+			}
+		}
+		if x != 1 {
+			panic("generatingPolynomial16 is not primitive")
+		}
+		logTable16, expTable16 = logs, exps
+	})
+}
+
+// gal16Multiply multiplies to elements of the field.
+func gal16Multiply(a, b uint16) uint16 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable16[int(logTable16[a])+int(logTable16[b])]
+}
+
+// gal16Divide is inverse of gal16Multiply.
+func gal16Divide(a, b uint16) uint16 {
+	if a == 0 {
+		return 0
+	}
+	if b == 0 {
+		panic("Argument 'divisor' is 0")
+	}
+	logResult := int(logTable16[a]) - int(logTable16[b])
+	if logResult < 0 {
+		logResult += fieldSize16 - 1
+	}
+	return expTable16[logResult]
+}
+
+// Computes a**n.
+//
+// The result will be the same as multiplying a times itself n times.
+func gal16Exp(a uint16, n int) uint16 {
+	if n == 0 {
+		return 1
+	}
+	if a == 0 {
+		return 0
+	}
+	logResult := (int(logTable16[a]) * n) % (fieldSize16 - 1)
+	return expTable16[logResult]
+}
+
+// gal16MulSlice multiplies every symbol in 'in' with c, and stores
+// the result in out.
+// Symbols are stored as 2 bytes, little endian.
+func gal16MulSlice(c uint16, in, out []byte) {
+	if c == 0 {
+		for i := range out[:len(in)] {
+			out[i] = 0
+		}
+		return
+	}
+	logC := int(logTable16[c])
+	for i := 0; i+1 < len(in); i += 2 {
+		v := uint16(in[i]) | uint16(in[i+1])<<8
+		if v != 0 {
+			v = expTable16[logC+int(logTable16[v])]
+		}
+		out[i], out[i+1] = byte(v), byte(v>>8)
+	}
+}
+
+// gal16MulSliceXor multiplies every symbol in 'in' with c, and adds
+// the result to out.
+// Symbols are stored as 2 bytes, little endian.
+func gal16MulSliceXor(c uint16, in, out []byte) {
+	if c == 0 {
+		return
+	}
+	logC := int(logTable16[c])
+	for i := 0; i+1 < len(in); i += 2 {
+		v := uint16(in[i]) | uint16(in[i+1])<<8
+		if v == 0 {
+			continue
+		}
+		v = expTable16[logC+int(logTable16[v])]
+		out[i] ^= byte(v)
+		out[i+1] ^= byte(v >> 8)
+	}
+}
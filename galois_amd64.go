@@ -1,14 +1,11 @@
 //+build !noasm
 //+build !appengine
+//+build !rs_pure
 
 // Copyright 2015, Klaus Post, see LICENSE for details.
 
 package reedsolomon
 
-import (
-	"github.com/klauspost/cpuid"
-)
-
 //go:noescape
 func galMulSSSE3(low, high, in, out []byte)
 
@@ -40,36 +37,77 @@ func galMulSSSE3Xor(low, high, in, out []byte) {
 }
 */
 
-func galMulSlice(c byte, in, out []byte) {
+//go:noescape
+func sliceXorSSE2(in, out []byte)
+
+//go:noescape
+func sliceXorAVX2(in, out []byte)
+
+// sliceXorAsm is called before the assembly XOR functions by tests,
+// if set.
+var sliceXorAsm func()
+
+// sliceXor will XOR in into out.
+// SSE2 is part of the base amd64 instruction set, so it is used
+// if AVX2 is not available, but assembly is otherwise enabled.
+func sliceXor(in, out []byte, o *options) {
+	var done int
+	if o.useAVX2 {
+		if sliceXorAsm != nil {
+			sliceXorAsm()
+		}
+		sliceXorAVX2(in, out)
+		done = (len(in) >> 6) << 6
+	} else if o.useSSSE3 {
+		if sliceXorAsm != nil {
+			sliceXorAsm()
+		}
+		sliceXorSSE2(in, out)
+		done = (len(in) >> 5) << 5
+	}
+	for i := done; i < len(in); i++ {
+		out[i] ^= in[i]
+	}
+}
+
+func galMulSlice(c byte, in, out []byte, o *options) {
+	if c == 1 {
+		copy(out, in)
+		return
+	}
 	var done int
-	if cpuid.CPU.AVX2() {
-		galMulAVX2(mulTableLow[c][:], mulTableHigh[c][:], in, out)
+	low, high, mt := o.field.tables(c)
+	if o.useAVX2 {
+		galMulAVX2(low[:], high[:], in, out)
 		done = (len(in) >> 5) << 5
-	} else if cpuid.CPU.SSSE3() {
-		galMulSSSE3(mulTableLow[c][:], mulTableHigh[c][:], in, out)
+	} else if !avx2Only && o.useSSSE3 {
+		galMulSSSE3(low[:], high[:], in, out)
 		done = (len(in) >> 4) << 4
 	}
 	remain := len(in) - done
 	if remain > 0 {
-		mt := mulTable[c]
 		for i := done; i < len(in); i++ {
 			out[i] = mt[in[i]]
 		}
 	}
 }
 
-func galMulSliceXor(c byte, in, out []byte) {
+func galMulSliceXor(c byte, in, out []byte, o *options) {
+	if c == 1 {
+		sliceXor(in, out, o)
+		return
+	}
 	var done int
-	if cpuid.CPU.AVX2() {
-		galMulAVX2Xor(mulTableLow[c][:], mulTableHigh[c][:], in, out)
+	low, high, mt := o.field.tables(c)
+	if o.useAVX2 {
+		galMulAVX2Xor(low[:], high[:], in, out)
 		done = (len(in) >> 5) << 5
-	} else if cpuid.CPU.SSSE3() {
-		galMulSSSE3Xor(mulTableLow[c][:], mulTableHigh[c][:], in, out)
+	} else if !avx2Only && o.useSSSE3 {
+		galMulSSSE3Xor(low[:], high[:], in, out)
 		done = (len(in) >> 4) << 4
 	}
 	remain := len(in) - done
 	if remain > 0 {
-		mt := mulTable[c]
 		for i := done; i < len(in); i++ {
 			out[i] ^= mt[in[i]]
 		}
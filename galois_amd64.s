@@ -1,4 +1,5 @@
 //+build !noasm !appengine
+//+build !rs_pure
 
 // Copyright 2015, Klaus Post, see LICENSE for details.
 
@@ -162,3 +163,56 @@ done_avx2:
 
 	BYTE $0xc5; BYTE $0xf8; BYTE $0x77 // VZEROUPPER
 	RET
+
+// func sliceXorSSE2(in, out []byte)
+TEXT ·sliceXorSSE2(SB), 7, $0
+	MOVQ  in+0(FP), SI      // SI: &in
+	MOVQ  in_len+8(FP), R9  // R9: len(in)
+	MOVQ  out+24(FP), DX    // DX: &out
+	SHRQ  $5, R9            // len(in) / 32
+	TESTQ R9, R9
+	JZ    done_xor_sse2
+
+loopback_xor_sse2:
+	MOVOU (SI), X0
+	MOVOU 16(SI), X1
+	MOVOU (DX), X2
+	MOVOU 16(DX), X3
+	PXOR  X0, X2
+	PXOR  X1, X3
+	MOVOU X2, (DX)
+	MOVOU X3, 16(DX)
+
+	ADDQ $32, SI       // in+=32
+	ADDQ $32, DX       // out+=32
+	SUBQ $1, R9
+	JNZ  loopback_xor_sse2
+
+done_xor_sse2:
+	RET
+
+// func sliceXorAVX2(in, out []byte)
+TEXT ·sliceXorAVX2(SB), 7, $0
+	MOVQ  in+0(FP), SI      // SI: &in
+	MOVQ  in_len+8(FP), R9  // R9: len(in)
+	MOVQ  out+24(FP), DX    // DX: &out
+	SHRQ  $6, R9            // len(in) / 64
+	TESTQ R9, R9
+	JZ    done_xor_avx2_64
+
+loopback_xor_avx2_64:
+	LONG $0x066ffec5             // VMOVDQU YMM0, [rsi]
+	LONG $0x4e6ffec5; BYTE $0x20 // VMOVDQU YMM1, [rsi+32]
+	LONG $0x02effdc5             // VPXOR   YMM0, YMM0, [rdx]
+	LONG $0x4aeff5c5; BYTE $0x20 // VPXOR   YMM1, YMM1, [rdx+32]
+	LONG $0x027ffec5             // VMOVDQU [rdx], YMM0
+	LONG $0x4a7ffec5; BYTE $0x20 // VMOVDQU [rdx+32], YMM1
+
+	ADDQ $64, SI       // in+=64
+	ADDQ $64, DX       // out+=64
+	SUBQ $1, R9
+	JNZ  loopback_xor_avx2_64
+
+done_xor_avx2_64:
+	BYTE $0xc5; BYTE $0xf8; BYTE $0x77 // VZEROUPPER
+	RET
@@ -0,0 +1,44 @@
+//+build !noasm
+//+build !appengine
+//+build !rs_pure
+
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Test that WithPureGo also keeps the XOR of coefficient one out of
+// the assembly functions.
+func TestPureGoSliceXor(t *testing.T) {
+	calls := 0
+	sliceXorAsm = func() { calls++ }
+	defer func() { sliceXorAsm = nil }()
+
+	in, out := make([]byte, 1000), make([]byte, 1000)
+	fillRandom(in)
+	fillRandom(out)
+	for _, pureGo := range []bool{true, false} {
+		enc, err := New(4, 1, WithPureGo(pureGo))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := enc.(*reedSolomon)
+		want := make([]byte, len(out))
+		for i := range want {
+			want[i] = in[i] ^ out[i]
+		}
+		calls = 0
+		galMulSliceXor(1, in, out, &r.o)
+		if !bytes.Equal(out, want) {
+			t.Errorf("pure go %v: XOR mismatch", pureGo)
+		}
+		if pureGo && calls != 0 {
+			t.Errorf("expected no assembly XOR calls with WithPureGo, got %d", calls)
+		}
+		if !pureGo && r.Backend() != "pure-go" && calls == 0 {
+			t.Error("expected assembly XOR calls")
+		}
+	}
+}
@@ -0,0 +1,70 @@
+//+build !noasm
+//+build !appengine
+//+build !rs_pure
+
+// Copyright 2015, Klaus Post, see LICENSE for details.
+
+package reedsolomon
+
+import "encoding/binary"
+
+func init() {
+	// NEON is part of the base arm64 instruction set.
+	defaultOptions.useNEON = true
+}
+
+//go:noescape
+func galMulNEON(low, high, in, out []byte)
+
+//go:noescape
+func galMulNEONXor(low, high, in, out []byte)
+
+// sliceXor will XOR in into out, 8 bytes at a time.
+func sliceXor(in, out []byte, o *options) {
+	done := (len(in) >> 3) << 3
+	for i := 0; i < done; i += 8 {
+		v := binary.LittleEndian.Uint64(out[i:]) ^ binary.LittleEndian.Uint64(in[i:])
+		binary.LittleEndian.PutUint64(out[i:], v)
+	}
+	for i := done; i < len(in); i++ {
+		out[i] ^= in[i]
+	}
+}
+
+func galMulSlice(c byte, in, out []byte, o *options) {
+	if c == 1 {
+		copy(out, in)
+		return
+	}
+	var done int
+	low, high, mt := o.field.tables(c)
+	if o.useNEON {
+		galMulNEON(low[:], high[:], in, out)
+		done = (len(in) >> 5) << 5
+	}
+	remain := len(in) - done
+	if remain > 0 {
+		for i := done; i < len(in); i++ {
+			out[i] = mt[in[i]]
+		}
+	}
+}
+
+func galMulSliceXor(c byte, in, out []byte, o *options) {
+	if c == 1 {
+		sliceXor(in, out, o)
+		return
+	}
+	var done int
+	low, high, mt := o.field.tables(c)
+	if o.useNEON {
+		galMulNEONXor(low[:], high[:], in, out)
+		done = (len(in) >> 5) << 5
+	}
+	remain := len(in) - done
+	if remain > 0 {
+		for i := done; i < len(in); i++ {
+			out[i] ^= mt[in[i]]
+		}
+	}
+}
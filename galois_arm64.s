@@ -0,0 +1,79 @@
+//+build !noasm
+//+build !appengine
+//+build !rs_pure
+
+// Copyright 2015, Klaus Post, see LICENSE for details.
+
+// Same algorithm as the SSSE3 version, see galois_amd64.s.
+// NEON is always available on arm64, so no CPU detection is needed.
+
+// func galMulNEON(low, high, in, out []byte)
+TEXT ·galMulNEON(SB), 7, $0
+	MOVD low+0(FP), R10     // R10: &low
+	MOVD high+24(FP), R11   // R11: &high
+	MOVD in+48(FP), R1      // R1: &in
+	MOVD in_len+56(FP), R2  // R2: len(in)
+	MOVD out+72(FP), R5     // R5: &out
+	LSR  $5, R2             // len(in) / 32
+	CBZ  R2, done
+
+	VLD1 (R10), [V6.B16] // V6: low
+	VLD1 (R11), [V7.B16] // V7: high
+	MOVD $15, R3         // R3: low mask
+	VDUP R3, V8.B16      // V8: lomask (unpacked)
+
+loopback:
+	VLD1.P 32(R1), [V0.B16, V1.B16] // in[x]
+	VUSHR  $4, V0.B16, V2.B16       // V2: high input
+	VUSHR  $4, V1.B16, V3.B16       // V3: high input
+	VAND   V8.B16, V0.B16, V0.B16   // V0: low input
+	VAND   V8.B16, V1.B16, V1.B16   // V1: low input
+	VTBL   V0.B16, [V6.B16], V0.B16 // V0: mul low part
+	VTBL   V1.B16, [V6.B16], V1.B16 // V1: mul low part
+	VTBL   V2.B16, [V7.B16], V2.B16 // V2: mul high part
+	VTBL   V3.B16, [V7.B16], V3.B16 // V3: mul high part
+	VEOR   V0.B16, V2.B16, V0.B16   // V0: Result
+	VEOR   V1.B16, V3.B16, V1.B16   // V1: Result
+	VST1.P [V0.B16, V1.B16], 32(R5) // Store
+	SUBS   $1, R2
+	BNE    loopback
+
+done:
+	RET
+
+// func galMulNEONXor(low, high, in, out []byte)
+TEXT ·galMulNEONXor(SB), 7, $0
+	MOVD low+0(FP), R10     // R10: &low
+	MOVD high+24(FP), R11   // R11: &high
+	MOVD in+48(FP), R1      // R1: &in
+	MOVD in_len+56(FP), R2  // R2: len(in)
+	MOVD out+72(FP), R5     // R5: &out
+	LSR  $5, R2             // len(in) / 32
+	CBZ  R2, done_xor
+
+	VLD1 (R10), [V6.B16] // V6: low
+	VLD1 (R11), [V7.B16] // V7: high
+	MOVD $15, R3         // R3: low mask
+	VDUP R3, V8.B16      // V8: lomask (unpacked)
+
+loopback_xor:
+	VLD1.P 32(R1), [V0.B16, V1.B16] // in[x]
+	VLD1   (R5), [V4.B16, V5.B16]   // out[x]
+	VUSHR  $4, V0.B16, V2.B16       // V2: high input
+	VUSHR  $4, V1.B16, V3.B16       // V3: high input
+	VAND   V8.B16, V0.B16, V0.B16   // V0: low input
+	VAND   V8.B16, V1.B16, V1.B16   // V1: low input
+	VTBL   V0.B16, [V6.B16], V0.B16 // V0: mul low part
+	VTBL   V1.B16, [V6.B16], V1.B16 // V1: mul low part
+	VTBL   V2.B16, [V7.B16], V2.B16 // V2: mul high part
+	VTBL   V3.B16, [V7.B16], V3.B16 // V3: mul high part
+	VEOR   V0.B16, V2.B16, V0.B16   // V0: Result
+	VEOR   V1.B16, V3.B16, V1.B16   // V1: Result
+	VEOR   V4.B16, V0.B16, V0.B16   // V0: Result xor existing out
+	VEOR   V5.B16, V1.B16, V1.B16   // V1: Result xor existing out
+	VST1.P [V0.B16, V1.B16], 32(R5) // Store
+	SUBS   $1, R2
+	BNE    loopback_xor
+
+done_xor:
+	RET
@@ -1,18 +1,28 @@
-//+build !amd64 noasm appengine
+//+build !amd64,!arm64 noasm appengine rs_pure
 
 // Copyright 2015, Klaus Post, see LICENSE for details.
 
 package reedsolomon
 
-func galMulSlice(c byte, in, out []byte) {
-	mt := mulTable[c]
+func galMulSlice(c byte, in, out []byte, o *options) {
+	if c == 1 {
+		copy(out, in)
+		return
+	}
+	_, _, mt := o.field.tables(c)
 	for n, input := range in {
 		out[n] = mt[input]
 	}
 }
 
-func galMulSliceXor(c byte, in, out []byte) {
-	mt := mulTable[c]
+func galMulSliceXor(c byte, in, out []byte, o *options) {
+	if c == 1 {
+		for n, input := range in {
+			out[n] ^= input
+		}
+		return
+	}
+	_, _, mt := o.field.tables(c)
 	for n, input := range in {
 		out[n] ^= mt[input]
 	}
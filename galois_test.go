@@ -9,6 +9,7 @@ package reedsolomon
 
 import (
 	"bytes"
+	"math"
 	"testing"
 )
 
@@ -131,13 +132,13 @@ func TestGalois(t *testing.T) {
 	// Test slices (>16 entries to test assembler)
 	in := []byte{0, 1, 2, 3, 4, 5, 6, 10, 50, 100, 150, 174, 201, 255, 99, 32, 67, 85}
 	out := make([]byte, len(in))
-	galMulSlice(25, in, out)
+	galMulSlice(25, in, out, &defaultOptions)
 	expect := []byte{0x0, 0x19, 0x32, 0x2b, 0x64, 0x7d, 0x56, 0xfa, 0xb8, 0x6d, 0xc7, 0x85, 0xc3, 0x1f, 0x22, 0x7, 0x25, 0xfe}
 	if 0 != bytes.Compare(out, expect) {
 		t.Errorf("got %#v, expected %#v", out, expect)
 	}
 
-	galMulSlice(177, in, out)
+	galMulSlice(177, in, out, &defaultOptions)
 	expect = []byte{0x0, 0xb1, 0x7f, 0xce, 0xfe, 0x4f, 0x81, 0x9e, 0x3, 0x6, 0xe8, 0x75, 0xbd, 0x40, 0x36, 0xa3, 0x95, 0xcb}
 	if 0 != bytes.Compare(out, expect) {
 		t.Errorf("got %#v, expected %#v", out, expect)
@@ -153,3 +154,125 @@ func TestGalois(t *testing.T) {
 		t.Fatal("galExp(13, 7) != 43")
 	}
 }
+
+// Test the field axioms for all elements with the exported functions.
+func TestGFAxioms(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		a := byte(i)
+		for j := 0; j < 256; j++ {
+			b := byte(j)
+			for k := 0; k < 256; k++ {
+				c := byte(k)
+				if GFMul(a, GFMul(b, c)) != GFMul(GFMul(a, b), c) {
+					t.Fatalf("multiply not associative for %d, %d, %d", a, b, c)
+				}
+				if GFMul(a, b^c) != GFMul(a, b)^GFMul(a, c) {
+					t.Fatalf("multiply not distributive for %d, %d, %d", a, b, c)
+				}
+			}
+			if b == 0 {
+				continue
+			}
+			q, err := GFDiv(a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if GFMul(q, b) != a {
+				t.Fatalf("GFMul(GFDiv(%d, %d), %d) != %d", a, b, b, a)
+			}
+		}
+		if a == 0 {
+			continue
+		}
+		inv, _ := GFDiv(1, a)
+		if GFMul(a, inv) != 1 {
+			t.Fatalf("%d has no multiplicative inverse", a)
+		}
+		// The multiplicative group has order 255.
+		if GFExp(a, 255) != 1 || GFExp(a, 254) != inv {
+			t.Fatalf("GFExp(%d, n) does not cycle with order 255", a)
+		}
+		x := byte(1)
+		for n := 0; n < 10; n++ {
+			if GFExp(a, n) != x {
+				t.Fatalf("GFExp(%d, %d) mismatch", a, n)
+			}
+			x = GFMul(x, a)
+		}
+		for _, n := range []int{1, 2, 7, 254, 1000, math.MaxInt / 3, math.MaxInt - 255} {
+			x := GFExp(a, n)
+			if GFExp(a, -n) != GFExp(inv, n) || GFMul(x, GFExp(a, -n)) != 1 {
+				t.Fatalf("GFExp(%d, %d) is not the inverse of GFExp(%d, %d)", a, -n, a, n)
+			}
+			if GFExp(a, n+255) != x || GFExp(a, n-255) != x {
+				t.Fatalf("GFExp(%d, %d) does not cycle with order 255", a, n)
+			}
+		}
+		for _, n := range []int{510, -255, math.MaxInt / 255 * 255, math.MinInt / 255 * 255} {
+			if GFExp(a, n) != 1 {
+				t.Fatalf("GFExp(%d, %d) is not 1", a, n)
+			}
+		}
+	}
+	for _, n := range []int{1, -1, 255, math.MaxInt, math.MinInt} {
+		if GFExp(0, n) != 0 {
+			t.Errorf("GFExp(0, %d) is not 0", n)
+		}
+	}
+	_, err := GFDiv(1, 0)
+	if err != ErrDivideByZero {
+		t.Errorf("expected %v, got %v", ErrDivideByZero, err)
+	}
+}
+
+// Test that the assembly versions match the scalar output for all
+// multipliers, and lengths that aren't a multiple of the block size.
+func TestGalMulSliceAll(t *testing.T) {
+	in := make([]byte, 1000)
+	fillRandom(in)
+	for _, o := range backendOptions() {
+		for _, size := range []int{0, 1, 15, 16, 17, 31, 32, 33, 63, 64, 65, 1000} {
+			src := in[:size]
+			for c := 0; c < 256; c++ {
+				want := make([]byte, size)
+				for i, v := range src {
+					want[i] = mulTable[c][v]
+				}
+				got := make([]byte, size)
+				galMulSlice(byte(c), src, got, &o)
+				if !bytes.Equal(want, got) {
+					t.Fatalf("%s: galMulSlice(%d), size %d: got %v, expected %v", o.backend(), c, size, got, want)
+				}
+
+				// Xor with the result twice should give all zeros.
+				galMulSliceXor(byte(c), src, got, &o)
+				for i, v := range got {
+					if v != 0 {
+						t.Fatalf("%s: galMulSliceXor(%d), size %d: index %d is %d, expected 0", o.backend(), c, size, i, v)
+					}
+				}
+			}
+		}
+	}
+}
+
+func benchmarkGalMulSliceXor(b *testing.B, c byte, size int) {
+	in := make([]byte, size)
+	out := make([]byte, size)
+	fillRandom(in)
+	o := defaultOptions
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		galMulSliceXor(c, in, out, &o)
+	}
+}
+
+// Multiplying by 1 is a plain XOR, as used by an all-ones parity row.
+func BenchmarkGalMulSliceXor1x1M(b *testing.B) {
+	benchmarkGalMulSliceXor(b, 1, 1024*1024)
+}
+
+func BenchmarkGalMulSliceXor2x1M(b *testing.B) {
+	benchmarkGalMulSliceXor(b, 2, 1024*1024)
+}
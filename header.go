@@ -0,0 +1,89 @@
+package reedsolomon
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// HeaderSize is the number of bytes added in front of the data
+// by EncodeWithHeader.
+const HeaderSize = 8
+
+// ErrInvalidHeader is returned by JoinWithHeader if the length stored
+// in the header is bigger than the data in the shards.
+var ErrInvalidHeader = errors.New("invalid length header")
+
+// EncodeWithHeader splits data into shards and encodes the parity,
+// storing the length of data in a header, so JoinWithHeader can
+// recover the data without knowing its size.
+//
+// The header is the length of data as a big endian uint64, stored in
+// the first HeaderSize bytes of the data shards, followed by the data.
+// Since the header takes up space, each object uses up to HeaderSize
+// bytes more than when using Split.
+//
+// The data is copied, so it can be modified afterwards.
+// An empty data slice is allowed.
+func (r reedSolomon) EncodeWithHeader(data []byte) ([][]byte, error) {
+	buf := make([]byte, HeaderSize, HeaderSize+len(data))
+	binary.BigEndian.PutUint64(buf, uint64(len(data)))
+	buf = append(buf, data...)
+	shards, err := r.Split(buf)
+	if err != nil {
+		return nil, err
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		return nil, err
+	}
+	return shards, nil
+}
+
+// JoinWithHeader writes the data encoded by EncodeWithHeader to dst.
+//
+// Only the data shards are considered, and they must all be present,
+// so call ReconstructData first if any are missing.
+// If the header doesn't fit the shards, ErrInvalidHeader is returned.
+func (r reedSolomon) JoinWithHeader(dst io.Writer, shards [][]byte) error {
+	if len(shards) < r.DataShards {
+		return ErrShardCount
+	}
+	// The header may span several shards, if they are small.
+	var hdr [HeaderSize]byte
+	n, size := 0, 0
+	for _, shard := range shards[:r.DataShards] {
+		if len(shard) == 0 {
+			return ErrShardNoData
+		}
+		n += copy(hdr[n:], shard)
+		size += len(shard)
+	}
+	if n < HeaderSize {
+		return ErrInvalidHeader
+	}
+	length := binary.BigEndian.Uint64(hdr[:])
+	if length > uint64(size-HeaderSize) {
+		return ErrInvalidHeader
+	}
+	return r.Join(&skipWriter{w: dst, skip: HeaderSize}, shards, HeaderSize+int(length))
+}
+
+// skipWriter discards the first skip bytes written to it,
+// and forwards the rest to w.
+type skipWriter struct {
+	w    io.Writer
+	skip int
+}
+
+func (s *skipWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if s.skip >= n {
+		s.skip -= n
+		return n, nil
+	}
+	p = p[s.skip:]
+	s.skip = 0
+	_, err := s.w.Write(p)
+	return n, err
+}
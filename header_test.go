@@ -0,0 +1,61 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeWithHeader(t *testing.T) {
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 1, 2, 9, 10, 100, 10007} {
+		data := make([]byte, size)
+		fillRandom(data)
+		shards, err := r.EncodeWithHeader(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok, err := r.Verify(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("verification failed")
+		}
+		shards[0], shards[5] = nil, nil
+		err = r.ReconstructData(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		err = r.JoinWithHeader(&buf, shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Fatalf("size %d: data mismatch, got %d bytes", size, buf.Len())
+		}
+	}
+
+	shards, err := r.EncodeWithHeader(make([]byte, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards[0][0] = 1
+	var buf bytes.Buffer
+	err = r.JoinWithHeader(&buf, shards)
+	if err != ErrInvalidHeader {
+		t.Errorf("expected %v, got %v", ErrInvalidHeader, err)
+	}
+	err = r.JoinWithHeader(&buf, shards[:9])
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+	shards[3] = nil
+	err = r.JoinWithHeader(&buf, shards)
+	if err != ErrShardNoData {
+		t.Errorf("expected %v, got %v", ErrShardNoData, err)
+	}
+}
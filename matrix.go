@@ -101,6 +101,11 @@ func (m matrix) String() string {
 // Multiply multiplies this matrix (the one on the left) by another
 // matrix (the one on the right) and returns a new matrix with the result.
 func (m matrix) Multiply(right matrix) (matrix, error) {
+	return m.multiplyWith(nil, right)
+}
+
+// multiplyWith functions as Multiply, using the field f.
+func (m matrix) multiplyWith(f *galField, right matrix) (matrix, error) {
 	if len(m[0]) != len(right) {
 		return nil, fmt.Errorf("columns on left (%d) is different than rows on right (%d)", len(m[0]), len(right))
 	}
@@ -109,7 +114,7 @@ func (m matrix) Multiply(right matrix) (matrix, error) {
 		for c := range row {
 			var value byte
 			for i := range m[0] {
-				value ^= galMultiply(m[r][i], right[i][c])
+				value ^= f.multiply(m[r][i], right[i][c])
 			}
 			result[r][c] = value
 		}
@@ -181,25 +186,45 @@ func (m matrix) IsSquare() bool {
 	return len(m) == len(m[0])
 }
 
-// errSingular is returned if the matrix is singular and cannot be inversed
-var errSingular = errors.New("matrix is singular")
+// ErrSingular is returned if the matrix is singular and cannot be inversed.
+var ErrSingular = errors.New("matrix is singular")
 
-// errNotSquare is returned if attempting to inverse a non-square matrix.
-var errNotSquare = errors.New("only square matrices can be inverted")
+// ErrNotSquare is returned if attempting to inverse a non-square matrix.
+var ErrNotSquare = errors.New("only square matrices can be inverted")
+
+// InvertMatrix returns the inverse of a square matrix over GF(2^8),
+// using the same field as the encoder.
+// The matrix is given as rows, m[row][col], and is not modified.
+//
+// ErrSingular is returned if the matrix doesn't have an inverse,
+// and ErrNotSquare if the matrix isn't square, which includes empty
+// matrices and rows of different lengths.
+func InvertMatrix(m [][]byte) ([][]byte, error) {
+	in := matrix(m)
+	if in.Check() != nil {
+		return nil, ErrNotSquare
+	}
+	return in.Invert()
+}
 
 // Invert returns the inverse of this matrix.
 // Returns ErrSingular when the matrix is singular and doesn't have an inverse.
 // The matrix must be square, otherwise ErrNotSquare is returned.
 func (m matrix) Invert() (matrix, error) {
+	return m.invertWith(nil)
+}
+
+// invertWith functions as Invert, using the field f.
+func (m matrix) invertWith(f *galField) (matrix, error) {
 	if !m.IsSquare() {
-		return nil, errNotSquare
+		return nil, ErrNotSquare
 	}
 
 	size := len(m)
 	work, _ := identityMatrix(size)
 	work, _ = m.Augment(work)
 
-	err := work.gaussianElimination()
+	err := work.gaussianElimination(f)
 	if err != nil {
 		return nil, err
 	}
@@ -207,7 +232,7 @@ func (m matrix) Invert() (matrix, error) {
 	return work.SubMatrix(0, size, size, size*2)
 }
 
-func (m matrix) gaussianElimination() error {
+func (m matrix) gaussianElimination(f *galField) error {
 	rows := len(m)
 	columns := len(m[0])
 	// Clear out the part below the main diagonal and scale the main
@@ -225,13 +250,13 @@ func (m matrix) gaussianElimination() error {
 		}
 		// If we couldn't find one, the matrix is singular.
 		if m[r][r] == 0 {
-			return errSingular
+			return ErrSingular
 		}
 		// Scale to 1.
 		if m[r][r] != 1 {
-			scale := galDivide(1, m[r][r])
+			scale := f.divide(1, m[r][r])
 			for c := 0; c < columns; c++ {
-				m[r][c] = galMultiply(m[r][c], scale)
+				m[r][c] = f.multiply(m[r][c], scale)
 			}
 		}
 		// Make everything below the 1 be a 0 by subtracting
@@ -241,7 +266,7 @@ func (m matrix) gaussianElimination() error {
 			if m[rowBelow][r] != 0 {
 				scale := m[rowBelow][r]
 				for c := 0; c < columns; c++ {
-					m[rowBelow][c] ^= galMultiply(scale, m[r][c])
+					m[rowBelow][c] ^= f.multiply(scale, m[r][c])
 				}
 			}
 		}
@@ -253,7 +278,7 @@ func (m matrix) gaussianElimination() error {
 			if m[rowAbove][d] != 0 {
 				scale := m[rowAbove][d]
 				for c := 0; c < columns; c++ {
-					m[rowAbove][c] ^= galMultiply(scale, m[d][c])
+					m[rowAbove][c] ^= f.multiply(scale, m[d][c])
 				}
 
 			}
@@ -265,14 +290,14 @@ func (m matrix) gaussianElimination() error {
 // Create a Vandermonde matrix, which is guaranteed to have the
 // property that any subset of rows that forms a square matrix
 // is invertible.
-func vandermonde(rows, cols int) (matrix, error) {
+func vandermonde(rows, cols int, f *galField) (matrix, error) {
 	result, err := newMatrix(rows, cols)
 	if err != nil {
 		return nil, err
 	}
 	for r, row := range result {
 		for c := range row {
-			result[r][c] = galExp(byte(r), c)
+			result[r][c] = f.exp(byte(r), c)
 		}
 	}
 	return result, nil
@@ -0,0 +1,128 @@
+/**
+ * Matrix Algebra over a 16-bit Galois Field
+ */
+
+package reedsolomon
+
+// uint16[row][col]
+type matrix16 [][]uint16
+
+// newMatrix16 returns a matrix of zeros.
+func newMatrix16(rows, cols int) (matrix16, error) {
+	if rows <= 0 {
+		return nil, errInvalidRowSize
+	}
+	if cols <= 0 {
+		return nil, errInvalidColSize
+	}
+
+	m := matrix16(make([][]uint16, rows))
+	for i := range m {
+		m[i] = make([]uint16, cols)
+	}
+	return m, nil
+}
+
+// Invert returns the inverse of this matrix.
+// Returns ErrSingular when the matrix is singular and doesn't have an inverse.
+// The matrix must be square, otherwise ErrNotSquare is returned.
+func (m matrix16) Invert() (matrix16, error) {
+	size := len(m)
+	if size == 0 || size != len(m[0]) {
+		return nil, ErrNotSquare
+	}
+
+	// Augment with the identity matrix.
+	work, _ := newMatrix16(size, size*2)
+	for r := range m {
+		copy(work[r], m[r])
+		work[r][size+r] = 1
+	}
+
+	err := work.gaussianElimination()
+	if err != nil {
+		return nil, err
+	}
+
+	result, _ := newMatrix16(size, size)
+	for r := range result {
+		copy(result[r], work[r][size:])
+	}
+	return result, nil
+}
+
+func (m matrix16) gaussianElimination() error {
+	rows := len(m)
+	columns := len(m[0])
+	// Clear out the part below the main diagonal and scale the main
+	// diagonal to be 1.
+	for r := 0; r < rows; r++ {
+		// If the element on the diagonal is 0, find a row below
+		// that has a non-zero and swap them.
+		if m[r][r] == 0 {
+			for rowBelow := r + 1; rowBelow < rows; rowBelow++ {
+				if m[rowBelow][r] != 0 {
+					m[r], m[rowBelow] = m[rowBelow], m[r]
+					break
+				}
+			}
+		}
+		// If we couldn't find one, the matrix is singular.
+		if m[r][r] == 0 {
+			return ErrSingular
+		}
+		// Scale to 1.
+		if m[r][r] != 1 {
+			scale := gal16Divide(1, m[r][r])
+			for c := 0; c < columns; c++ {
+				m[r][c] = gal16Multiply(m[r][c], scale)
+			}
+		}
+		// Make everything below the 1 be a 0 by subtracting
+		// a multiple of it.
+		for rowBelow := r + 1; rowBelow < rows; rowBelow++ {
+			if m[rowBelow][r] != 0 {
+				scale := m[rowBelow][r]
+				for c := 0; c < columns; c++ {
+					m[rowBelow][c] ^= gal16Multiply(scale, m[r][c])
+				}
+			}
+		}
+	}
+
+	// Now clear the part above the main diagonal.
+	for d := 0; d < rows; d++ {
+		for rowAbove := 0; rowAbove < d; rowAbove++ {
+			if m[rowAbove][d] != 0 {
+				scale := m[rowAbove][d]
+				for c := 0; c < columns; c++ {
+					m[rowAbove][c] ^= gal16Multiply(scale, m[d][c])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// buildParityCauchy16 creates the parity rows of the encoding
+// matrix for GF(2^16).
+//
+// The full matrix has an identity matrix on top, which is left out
+// to keep memory usage down for big codes. The parity rows are a
+// Cauchy matrix, where row r and column c has the value 1/(r XOR c),
+// with r starting at dataShards.
+// Any square subset of rows of the full matrix is invertible.
+func buildParityCauchy16(dataShards, parityShards int) (matrix16, error) {
+	result, err := newMatrix16(parityShards, dataShards)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, row := range result {
+		r := dataShards + i
+		for c := range row {
+			result[i][c] = gal16Divide(1, uint16(r^c))
+		}
+	}
+	return result, nil
+}
@@ -97,3 +97,65 @@ func TestMatrixInverse2(t *testing.T) {
 		t.Fatal(str, "!=", expect)
 	}
 }
+
+func TestInvertMatrix(t *testing.T) {
+	in := [][]byte{
+		[]byte{56, 23, 98},
+		[]byte{3, 100, 200},
+		[]byte{45, 201, 123},
+	}
+	inv, err := InvertMatrix(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	str := matrix(inv).String()
+	expect := "[[175, 133, 33], [130, 13, 245], [112, 35, 126]]"
+	if str != expect {
+		t.Fatal(str, "!=", expect)
+	}
+	// Input should be unchanged.
+	str = matrix(in).String()
+	expect = "[[56, 23, 98], [3, 100, 200], [45, 201, 123]]"
+	if str != expect {
+		t.Fatal(str, "!=", expect)
+	}
+	// Inverting the inverse should give the input.
+	inv, err = InvertMatrix(inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matrix(inv).String() != expect {
+		t.Fatal(matrix(inv).String(), "!=", expect)
+	}
+
+	singular := [][][]byte{
+		{{0}},
+		{{1, 2}, {1, 2}},
+		{{4, 2}, {2, 1}},
+		{{1, 2, 3}, {0, 0, 0}, {5, 6, 7}},
+		{{1, 0, 0}, {0, 1, 0}, {1, 1, 0}},
+	}
+	for _, m := range singular {
+		_, err = InvertMatrix(m)
+		if err != ErrSingular {
+			t.Errorf("%v: expected %v, got %v", matrix(m), ErrSingular, err)
+		}
+	}
+
+	notSquare := [][][]byte{
+		nil,
+		{},
+		{{}},
+		{{1, 2}},
+		{{1}, {2}},
+		{{1, 2}, {3}},
+		{{1}, {2, 3}},
+		{{1, 2, 3}, {4, 5, 6}, {7, 8}},
+	}
+	for _, m := range notSquare {
+		_, err = InvertMatrix(m)
+		if err != ErrNotSquare {
+			t.Errorf("%v: expected %v, got %v", m, ErrNotSquare, err)
+		}
+	}
+}
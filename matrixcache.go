@@ -0,0 +1,84 @@
+package reedsolomon
+
+import (
+	"container/list"
+	"sync"
+)
+
+// defaultMatrixCacheSize is the number of decode matrices
+// kept by an encoder, unless WithMatrixCacheSize is used.
+const defaultMatrixCacheSize = 16
+
+// matrixCacheUnbounded is the cache size used for a cache
+// that never evicts any matrices.
+const matrixCacheUnbounded = -1
+
+// shardBitmap has a bit set for every shard used for decoding.
+type shardBitmap [4]uint64
+
+func (b *shardBitmap) set(i int) {
+	b[i>>6] |= 1 << uint(i&63)
+}
+
+// matrixCache is a least recently used cache of inverted
+// decode matrices, keyed by the shards used to create them.
+// It is safe for concurrent use.
+type matrixCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[shardBitmap]*list.Element
+}
+
+type matrixCacheEntry struct {
+	key shardBitmap
+	m   matrix
+}
+
+// newMatrixCache returns a cache holding up to size matrices.
+// If size is matrixCacheUnbounded, matrices are never evicted.
+func newMatrixCache(size int) *matrixCache {
+	return &matrixCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[shardBitmap]*list.Element),
+	}
+}
+
+// get returns the matrix stored for key, or nil if not found.
+// The returned matrix must not be modified.
+func (c *matrixCache) get(key shardBitmap) matrix {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*matrixCacheEntry).m
+}
+
+// put stores m for key, and evicts the least recently
+// used matrix if the cache is full.
+func (c *matrixCache) put(key shardBitmap, m matrix) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*matrixCacheEntry).m = m
+		return
+	}
+	c.items[key] = c.ll.PushFront(&matrixCacheEntry{key: key, m: m})
+	if c.size != matrixCacheUnbounded && c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*matrixCacheEntry).key)
+	}
+}
+
+// len returns the number of matrices in the cache.
+func (c *matrixCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
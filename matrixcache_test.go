@@ -0,0 +1,210 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMatrixCache(t *testing.T) {
+	c := newMatrixCache(2)
+	var a, b, d shardBitmap
+	a.set(0)
+	b.set(1)
+	d.set(200)
+	ma, mb, md := matrix{{1}}, matrix{{2}}, matrix{{3}}
+
+	c.put(a, ma)
+	c.put(b, mb)
+	if got := c.get(a); got == nil || got[0][0] != 1 {
+		t.Fatal("expected to find a")
+	}
+	// b is now least recently used.
+	c.put(d, md)
+	if c.len() != 2 {
+		t.Fatal("expected 2 entries, got", c.len())
+	}
+	if c.get(b) != nil {
+		t.Error("b should have been evicted")
+	}
+	if c.get(a) == nil || c.get(d) == nil {
+		t.Error("a and d should be cached")
+	}
+}
+
+func TestInversionTree(t *testing.T) {
+	r, err := New(6, 3, WithInversionTree(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := r.(*reedSolomon).cache
+	if cache == nil {
+		t.Fatal("cache should be enabled")
+	}
+	shards, err := r.Split(make([]byte, 6000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// All patterns with one missing data shard and one missing parity.
+	for d := 0; d < 6; d++ {
+		for p := 6; p < 9; p++ {
+			test := append([][]byte{}, shards...)
+			test[d], test[p] = nil, nil
+			err = r.Reconstruct(test)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// A single data shard is recreated without a matrix when the
+	// first parity shard is present, so only the patterns missing it
+	// are cached. None of them may be evicted.
+	if cache.len() != 6 {
+		t.Errorf("expected 6 cached matrices, got %d", cache.len())
+	}
+
+	for _, opt := range []Option{WithInversionTree(false), WithMatrixCacheSize(-1)} {
+		r, err = New(6, 3, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.(*reedSolomon).cache != nil {
+			t.Error("cache should be disabled")
+		}
+	}
+}
+
+func TestReconstructCached(t *testing.T) {
+	r, err := New(10, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := r.(*reedSolomon).cache
+	if cached == nil {
+		t.Fatal("cache should be enabled by default")
+	}
+	data := make([]byte, 50000)
+	fillRandom(data)
+	shards, err := r.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, missing := range [][]int{{0, 3}, {0, 3}, {1, 10}, {0, 3}, {2, 5, 7, 12}} {
+		test := make([][]byte, len(shards))
+		copy(test, shards)
+		for _, idx := range missing {
+			test[idx] = nil
+		}
+		err = r.Reconstruct(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range shards {
+			if !bytes.Equal(test[i], shards[i]) {
+				t.Fatalf("missing %v: shard %d mismatch", missing, i)
+			}
+		}
+	}
+	if cached.len() != 3 {
+		t.Errorf("expected 3 cached matrices, got %d", cached.len())
+	}
+
+	r, err = New(10, 4, WithMatrixCacheSize(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.(*reedSolomon).cache != nil {
+		t.Error("cache should be disabled")
+	}
+}
+
+func benchmarkReconstructCache(b *testing.B, cacheSize int) {
+	r, err := New(50, 20, WithMatrixCacheSize(cacheSize))
+	if err != nil {
+		b.Fatal(err)
+	}
+	shards := make([][]byte, 70)
+	for s := range shards {
+		shards[s] = make([]byte, 1024)
+	}
+	for s := 0; s < 50; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		b.Fatal(err)
+	}
+	test := make([][]byte, len(shards))
+
+	b.SetBytes(1024 * 50)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(test, shards)
+		test[1], test[17], test[33] = nil, nil, nil
+		err = r.Reconstruct(test)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReconstructCache50x20x1K(b *testing.B) {
+	benchmarkReconstructCache(b, defaultMatrixCacheSize)
+}
+
+func BenchmarkReconstructNoCache50x20x1K(b *testing.B) {
+	benchmarkReconstructCache(b, 0)
+}
+
+// benchmarkReconstructPatterns reconstructs while cycling through
+// a number of different failure patterns.
+func benchmarkReconstructPatterns(b *testing.B, opt Option, patterns int) {
+	r, err := New(20, 4, opt)
+	if err != nil {
+		b.Fatal(err)
+	}
+	shards := make([][]byte, 24)
+	for s := range shards {
+		shards[s] = make([]byte, 1024)
+	}
+	for s := 0; s < 20; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		b.Fatal(err)
+	}
+	test := make([][]byte, len(shards))
+
+	b.SetBytes(1024 * 20)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := i % patterns
+		copy(test, shards)
+		test[p%20], test[(p/20+p+1)%20] = nil, nil
+		err = r.Reconstruct(test)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReconstructPatternsTree(b *testing.B) {
+	benchmarkReconstructPatterns(b, WithInversionTree(true), 100)
+}
+
+func BenchmarkReconstructPatternsLRU(b *testing.B) {
+	benchmarkReconstructPatterns(b, WithMatrixCacheSize(defaultMatrixCacheSize), 100)
+}
+
+func BenchmarkReconstructPatternsNone(b *testing.B) {
+	benchmarkReconstructPatterns(b, WithInversionTree(false), 100)
+}
@@ -0,0 +1,11 @@
+//+build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd appengine
+
+package reedsolomon
+
+import "os"
+
+// EncodeMmap is not supported on this platform,
+// and always returns ErrMmapNotSupported.
+func (r reedSolomon) EncodeMmap(dataFiles, parityFiles []*os.File) error {
+	return ErrMmapNotSupported
+}
@@ -0,0 +1,100 @@
+//+build linux darwin dragonfly freebsd netbsd openbsd
+//+build !appengine
+
+package reedsolomon
+
+import (
+	"os"
+	"syscall"
+)
+
+// EncodeMmap encodes parity for shards stored in files, by mapping
+// the files into memory and running the coding directly on the
+// mapped memory, so files larger than memory can be encoded.
+//
+// All data files must have the same size, which is the shard size.
+// The parity files are truncated or extended to the same size, so
+// they must be opened for reading and writing.
+//
+// The mapped pages are read and written by the operating system on
+// demand, so the first access to each page causes a page fault and
+// a read. An I/O error while accessing the mapped memory cannot be
+// returned, and will crash the program with SIGBUS, so this should
+// only be used with reliable, local files.
+// The parity files are synced to disk before EncodeMmap returns.
+//
+// The files are not closed, and their positions are not changed.
+//
+// A non-systematic encoder returns ErrNonSystematic, since the encoded
+// data shards would replace the content of the data files.
+func (r reedSolomon) EncodeMmap(dataFiles, parityFiles []*os.File) error {
+	if r.mix != nil {
+		return ErrNonSystematic
+	}
+	if len(dataFiles) != r.DataShards || len(parityFiles) != r.ParityShards {
+		return ErrShardCount
+	}
+	var size int64 = -1
+	for _, f := range dataFiles {
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if size >= 0 && fi.Size() != size {
+			return ErrShardSize
+		}
+		size = fi.Size()
+	}
+	if size <= 0 {
+		return ErrShardNoData
+	}
+	if int64(int(size)) != size {
+		return ErrShardSize
+	}
+
+	var mapped [][]byte
+	defer func() {
+		for _, m := range mapped {
+			syscall.Munmap(m)
+		}
+	}()
+	shards := make([][]byte, 0, r.Shards)
+	for _, f := range dataFiles {
+		m, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+		if err != nil {
+			return err
+		}
+		mapped = append(mapped, m)
+		shards = append(shards, m)
+	}
+	for _, f := range parityFiles {
+		err := f.Truncate(size)
+		if err != nil {
+			return err
+		}
+		m, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+		if err != nil {
+			return err
+		}
+		mapped = append(mapped, m)
+		shards = append(shards, m)
+	}
+
+	r.codeSomeShards(r.parity, shards[:r.DataShards], shards[r.DataShards:], r.ParityShards, int(size))
+
+	// Unmap before syncing, so all written pages are flushed.
+	for _, m := range mapped {
+		err := syscall.Munmap(m)
+		if err != nil {
+			return err
+		}
+	}
+	mapped = nil
+	for _, f := range parityFiles {
+		err := f.Sync()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+//+build linux darwin dragonfly freebsd netbsd openbsd
+//+build !appengine
+
+package reedsolomon
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncodeMmap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reedsolomon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := newExt(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const size = 100000
+	shards := make([][]byte, 6)
+	files := make([]*os.File, 6)
+	for i := range files {
+		shards[i] = make([]byte, size)
+		if i < 4 {
+			fillRandom(shards[i])
+		}
+		files[i], err = os.OpenFile(filepath.Join(dir, string(rune('a'+i))), os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer files[i].Close()
+		if i < 4 {
+			_, err = files[i].Write(shards[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// Leave existing content in a parity file.
+	_, err = files[5].Write(make([]byte, size*2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.EncodeMmap(files[:4], files[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 4; i < 6; i++ {
+		got, err := ioutil.ReadFile(files[i].Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, shards[i]) {
+			t.Fatal("parity file mismatch", i)
+		}
+	}
+
+	err = files[3].Truncate(size - 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.EncodeMmap(files[:4], files[4:])
+	if err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+	err = r.EncodeMmap(files[:3], files[4:])
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+
+	// The data files must not be replaced by the encoded data shards.
+	r, err = newExt(4, 2, WithNonSystematic())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = files[3].Truncate(size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.EncodeMmap(files[:4], files[4:])
+	if err != ErrNonSystematic {
+		t.Errorf("expected %v, got %v", ErrNonSystematic, err)
+	}
+	got, err := ioutil.ReadFile(files[0].Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, shards[0]) {
+		t.Error("data file was modified")
+	}
+}
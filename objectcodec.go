@@ -0,0 +1,176 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+)
+
+// Shard is a single encoded shard produced by ObjectCodec.
+type Shard struct {
+	Index    int    // Index of the shard in the encoded set.
+	Checksum uint32 // CRC32C (Castagnoli) of Data.
+	Data     []byte // Shard content.
+}
+
+// Manifest describes an object encoded by ObjectCodec, or the
+// configuration of any encoder.
+// It must be stored together with the shards, since it is needed
+// to decode the object, and NewFromManifest can create a matching
+// encoder from it.
+type Manifest struct {
+	Size          int64 // Size of the original object in bytes.
+	ShardSize     int   // Size of every shard in bytes.
+	DataShards    int   // Number of data shards of the scheme.
+	ParityShards  int   // Number of parity shards of the scheme.
+	Cauchy        bool  // WithCauchyMatrix was used.
+	NonSystematic bool  // WithNonSystematic was used.
+	FieldPoly     int   // Polynomial given to WithFieldPoly, 0 for the default.
+	NibbleField   bool  // WithNibbleField was used.
+}
+
+// manifest returns a Manifest describing the scheme of the encoder.
+// The size fields are not set.
+func (r reedSolomon) manifest() Manifest {
+	return Manifest{
+		DataShards:    r.DataShards,
+		ParityShards:  r.ParityShards,
+		Cauchy:        r.o.useCauchy && !r.o.nonSystematic,
+		NonSystematic: r.o.nonSystematic,
+		FieldPoly:     r.o.fieldPoly,
+		NibbleField:   r.o.nibbleField,
+	}
+}
+
+// sameScheme returns true if m and o describe the same scheme.
+func (m Manifest) sameScheme(o Manifest) bool {
+	poly := func(p int) int {
+		if p == 0x100+generatingPolynomial {
+			return 0
+		}
+		return p
+	}
+	return m.DataShards == o.DataShards && m.ParityShards == o.ParityShards &&
+		m.Cauchy == o.Cauchy && m.NonSystematic == o.NonSystematic &&
+		poly(m.FieldPoly) == poly(o.FieldPoly) && m.NibbleField == o.NibbleField
+}
+
+// NewFromManifest creates an encoder with the scheme described by m,
+// so shards can be decoded without knowing how they were encoded.
+// Options that do not change the output, like WithPureGo, can be given
+// in opts, while options selecting the scheme are taken from m.
+func NewFromManifest(m Manifest, opts ...Option) (Encoder, error) {
+	opts = append(opts[:len(opts):len(opts)], func(o *options) {
+		o.useCauchy = m.Cauchy
+		o.nonSystematic = m.NonSystematic
+		o.fieldPoly = m.FieldPoly
+		o.nibbleField = m.NibbleField
+	})
+	return New(m.DataShards, m.ParityShards, opts...)
+}
+
+// ErrInvalidManifest is returned by ObjectCodec.Decode if the
+// manifest does not describe an object encoded by the codec.
+var ErrInvalidManifest = errors.New("manifest does not match the codec")
+
+// ObjectCodec bundles splitting, encoding, checksums and size
+// information for complete objects.
+// Shards with a checksum that does not match their content are
+// treated as missing, so corrupted shards are never used to decode.
+// An ObjectCodec is safe for concurrent use.
+type ObjectCodec struct {
+	enc    *reedSolomon
+	scheme Manifest
+}
+
+// NewObjectCodec creates an ObjectCodec with the given number of
+// data and parity shards.
+// The options are given to New, and the scheme they select is
+// recorded in the manifest, so Decode refuses manifests written
+// with another scheme.
+func NewObjectCodec(dataShards, parityShards int, opts ...Option) (*ObjectCodec, error) {
+	enc, err := New(dataShards, parityShards, opts...)
+	if err != nil {
+		return nil, err
+	}
+	r := enc.(*reedSolomon)
+	return &ObjectCodec{enc: r, scheme: r.manifest()}, nil
+}
+
+// Encode splits data into shards and creates the parity.
+// The returned shards are in index order, and may share memory
+// with data, so data should not be modified afterwards.
+//
+// There must be at least 1 byte otherwise ErrShortData will be
+// returned.
+func (c *ObjectCodec) Encode(data []byte) ([]Shard, Manifest, error) {
+	if c.scheme.NonSystematic && len(data) > 0 {
+		// Encoding replaces the data shards, so never do it in data.
+		data = append([]byte{}, data...)
+	}
+	split, err := c.enc.Split(data)
+	if err != nil {
+		return nil, Manifest{}, err
+	}
+	err = c.enc.Encode(split)
+	if err != nil {
+		return nil, Manifest{}, err
+	}
+	shards := make([]Shard, len(split))
+	for i, s := range split {
+		shards[i] = Shard{Index: i, Checksum: crc32.Checksum(s, castagnoli), Data: s}
+	}
+	m := c.scheme
+	m.Size = int64(len(data))
+	m.ShardSize = len(split[0])
+	return shards, m, nil
+}
+
+// Decode returns the object described by m from the given shards.
+//
+// Shards may be given in any order, and missing shards are simply left
+// out. Shards that have the wrong size or do not match their checksum
+// are ignored. If fewer than DataShards valid shards remain,
+// ErrTooManyFailures is returned.
+//
+// If m was not produced by a codec with the same scheme,
+// ErrInvalidManifest is returned. A shard with an index outside the
+// scheme, or an index given twice, returns ErrInvalidShardIndex.
+// The shards are not modified.
+func (c *ObjectCodec) Decode(shards []Shard, m Manifest) ([]byte, error) {
+	if !m.sameScheme(c.scheme) {
+		return nil, ErrInvalidManifest
+	}
+	if m.ShardSize <= 0 || m.Size < 0 || m.Size > int64(m.ShardSize)*int64(m.DataShards) {
+		return nil, ErrInvalidManifest
+	}
+	set := make([][]byte, m.DataShards+m.ParityShards)
+	seen := make([]bool, len(set))
+	for _, s := range shards {
+		if s.Index < 0 || s.Index >= len(set) || seen[s.Index] {
+			return nil, ErrInvalidShardIndex
+		}
+		seen[s.Index] = true
+		if len(s.Data) != m.ShardSize || crc32.Checksum(s.Data, castagnoli) != s.Checksum {
+			continue
+		}
+		set[s.Index] = s.Data
+	}
+	if c.scheme.NonSystematic {
+		// Data shards are decoded in place.
+		for i := range set[:m.DataShards] {
+			set[i] = append([]byte(nil), set[i]...)
+		}
+	}
+	err := c.enc.DecodeData(set)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Grow(int(m.Size))
+	err = c.enc.Join(&buf, set, int(m.Size))
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,135 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestObjectCodec(t *testing.T) {
+	c, err := NewObjectCodec(5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 10007)
+	fillRandom(data)
+	shards, m, err := c.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shards) != 8 || m.Size != int64(len(data)) {
+		t.Fatalf("unexpected encoding: %d shards, manifest %+v", len(shards), m)
+	}
+
+	got, err := c.Decode(shards, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("decoded data mismatch")
+	}
+
+	// Shuffle, drop two shards and corrupt one.
+	rand.Shuffle(len(shards), func(i, j int) { shards[i], shards[j] = shards[j], shards[i] })
+	subset := append([]Shard{}, shards[2:]...)
+	bad := append([]byte{}, subset[0].Data...)
+	bad[0]++
+	subset[0].Data = bad
+	got, err = c.Decode(subset, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("decoded data mismatch")
+	}
+
+	// One more corrupted shard is too many.
+	bad = append([]byte{}, subset[1].Data...)
+	bad[0]++
+	subset[1].Data = bad
+	_, err = c.Decode(subset, m)
+	if !errors.Is(err, ErrTooManyFailures) {
+		t.Errorf("expected %v, got %v", ErrTooManyFailures, err)
+	}
+
+	_, err = c.Decode(append(shards, shards[0]), m)
+	if err != ErrInvalidShardIndex {
+		t.Errorf("expected %v, got %v", ErrInvalidShardIndex, err)
+	}
+	other := m
+	other.ParityShards = 2
+	_, err = c.Decode(shards, other)
+	if err != ErrInvalidManifest {
+		t.Errorf("expected %v, got %v", ErrInvalidManifest, err)
+	}
+	other = m
+	other.Size = int64(m.ShardSize*5 + 1)
+	_, err = c.Decode(shards, other)
+	if err != ErrInvalidManifest {
+		t.Errorf("expected %v, got %v", ErrInvalidManifest, err)
+	}
+	_, _, err = c.Encode(nil)
+	if err != ErrShortData {
+		t.Errorf("expected %v, got %v", ErrShortData, err)
+	}
+}
+
+func TestNewFromManifest(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithCauchyMatrix()},
+		{WithNonSystematic()},
+		{WithFieldPoly(0x12b)},
+		{WithNibbleField()},
+		{WithNibbleField(), WithNonSystematic()},
+	} {
+		c, err := NewObjectCodec(6, 3, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, 1000)
+		fillRandom(data)
+		orig := append([]byte{}, data...)
+		shards, m, err := c.Encode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, orig) {
+			t.Fatal("input was modified")
+		}
+		got, err := c.Decode(shards[2:], m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("%+v: decoded data mismatch", m)
+		}
+
+		// An encoder created from the manifest must reproduce the parity.
+		enc, err := NewFromManifest(m, WithPureGo(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		set := make([][]byte, 9)
+		for _, s := range shards {
+			set[s.Index] = s.Data
+		}
+		ok, err := enc.Verify(set)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("%+v: verification failed", m)
+		}
+
+		other, err := NewObjectCodec(6, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = other.Decode(shards, m)
+		if len(opts) > 0 && err != ErrInvalidManifest {
+			t.Errorf("%+v: expected %v, got %v", m, ErrInvalidManifest, err)
+		}
+	}
+}
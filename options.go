@@ -0,0 +1,335 @@
+package reedsolomon
+
+import "sync"
+
+// Option allows to override processing parameters.
+type Option func(*options)
+
+type options struct {
+	useAVX2, useSSSE3 bool
+	useNEON           bool
+	useCauchy         bool
+	nonSystematic     bool
+	nibbleField       bool
+	readAhead         int
+	lazyParity        bool
+	verifyReconstruct bool
+	reuseShards       bool
+	runner            func(tasks []func())
+	kernelBlockSize   int // 0 for whole shards
+	reconstructPlan   func(ReconstructPlan)
+	pool              *sync.Pool
+	matrixCacheSize   int
+	fieldPoly         int
+	field             *galField // nil for the default field
+}
+
+// defaultOptions are the options used if none are given.
+// CPU capabilities are filled in by the platform specific init,
+// so they are only set if an assembly version is compiled in.
+var defaultOptions = options{
+	matrixCacheSize: defaultMatrixCacheSize,
+}
+
+// backend returns the name of the Galois multiplication
+// implementation selected by the options.
+func (o *options) backend() string {
+	switch {
+	case o.useAVX2:
+		return "avx2"
+	case o.useSSSE3:
+		return "ssse3"
+	case o.useNEON:
+		return "neon"
+	}
+	return "pure-go"
+}
+
+// run runs the tasks in parallel, and returns when all are done.
+// The tasks are given to the runner set by WithRunner, or otherwise
+// each task runs in a new goroutine.
+func (o *options) run(tasks []func()) {
+	if o.runner != nil {
+		o.runner(tasks)
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(len(tasks))
+	for _, task := range tasks {
+		go func(task func()) {
+			defer wg.Done()
+			task()
+		}(task)
+	}
+	wg.Wait()
+}
+
+// WithRunner will make the encoder give the tasks it would otherwise
+// run in separate goroutines to run, for instance to execute them on
+// an existing worker pool.
+//
+// run must execute every task exactly once, and must not return until
+// all of them have completed. The tasks are independent, so they may
+// run in any order and in any goroutines, including sequentially in
+// the calling goroutine, but running them in parallel is faster.
+// The encoder may call run from several goroutines at once, if it is
+// used concurrently.
+//
+// This applies to the calculations of the encoder. Concurrent reads
+// and writes of a StreamEncoder created by NewStreamC, and the
+// goroutine used by WithReadAhead, still use their own goroutines.
+func WithRunner(run func(tasks []func())) Option {
+	return func(o *options) {
+		o.runner = run
+	}
+}
+
+// WithKernelBlockSize will make the encoder multiply the shards in
+// blocks of n bytes. The block of every input is multiplied into all
+// outputs before continuing with the next block, so the outputs can
+// stay in the CPU cache while all inputs are added.
+// The best size depends on the CPU and the number of shards, and can
+// be found with BenchmarkEncodeKernelBlockSize.
+//
+// n must be a multiple of 64, otherwise New returns
+// ErrInvalidKernelBlockSize. A size of 0 multiplies whole shards, or
+// the part of the shards given to each goroutine, which is the default.
+// This applies to encoding, reconstruction and the functions using
+// them, while Verify uses its own blocks.
+func WithKernelBlockSize(n int) Option {
+	return func(o *options) {
+		o.kernelBlockSize = n
+	}
+}
+
+// WithPureGo will force the encoder to use the scalar Go implementation
+// of the Galois field multiplication, regardless of the detected CPU
+// features. This can be used to work around a broken assembly path.
+// Passing false keeps the default auto-detection.
+func WithPureGo(enabled bool) Option {
+	return func(o *options) {
+		if enabled {
+			o.useAVX2, o.useSSSE3, o.useNEON = false, false, false
+		} else {
+			o.useAVX2, o.useSSSE3, o.useNEON = defaultOptions.useAVX2, defaultOptions.useSSSE3, defaultOptions.useNEON
+		}
+	}
+}
+
+// WithAVX2 allows to disable the AVX2 assembly, so SSSE3 is used on
+// CPUs supporting both, if it is not disabled as well.
+// Passing true keeps the default auto-detection, as AVX2 is never used
+// on CPUs without it.
+func WithAVX2(enabled bool) Option {
+	return func(o *options) {
+		o.useAVX2 = enabled && defaultOptions.useAVX2
+	}
+}
+
+// WithSSSE3 allows to disable the SSSE3 assembly.
+// Passing true keeps the default auto-detection, as SSSE3 is never used
+// on CPUs without it.
+func WithSSSE3(enabled bool) Option {
+	return func(o *options) {
+		o.useSSSE3 = enabled && defaultOptions.useSSSE3
+	}
+}
+
+// WithCauchyMatrix will make the encoder build a Cauchy style matrix.
+// The output of this is not compatible with the standard output.
+// A Cauchy matrix is used by some other implementations, and is
+// faster to create than the default matrix.
+func WithCauchyMatrix() Option {
+	return func(o *options) {
+		o.useCauchy = true
+	}
+}
+
+// WithVandermondeMatrix will make the encoder build a matrix from a
+// Vandermonde matrix, compatible with the Backblaze JavaReedSolomon
+// library. This is the default.
+func WithVandermondeMatrix() Option {
+	return func(o *options) {
+		o.useCauchy = false
+	}
+}
+
+// WithBufferPool will make the encoder take temporary buffers from
+// the supplied pool, and return them after use.
+// This can be used to share buffers between several encoders.
+// The pool must only contain *[]byte values, and the New function
+// may be nil. Buffers that are too small are discarded.
+// If not set, every encoder creates its own pool.
+func WithBufferPool(p *sync.Pool) Option {
+	return func(o *options) {
+		o.pool = p
+	}
+}
+
+// WithMatrixCacheSize sets the number of inverted decode matrices
+// the encoder keeps, so they are reused when the same set of shards
+// is missing again. The least recently used matrix is evicted when
+// the cache is full. A size of 0 or less disables the cache.
+// The default size is 16.
+func WithMatrixCacheSize(n int) Option {
+	return func(o *options) {
+		if n < 0 {
+			n = 0
+		}
+		o.matrixCacheSize = n
+	}
+}
+
+// WithInversionTree controls whether every inverted decode matrix is
+// kept, like the inversion tree of the upstream package.
+//
+// When enabled, a matrix is never inverted twice for the same set of
+// missing shards, which gives the best reconstruction speed when many
+// different failure patterns occur. The memory used is not bounded,
+// as every pattern seen adds a matrix of dataShards^2 bytes.
+// When disabled, no matrices are kept, which uses the least memory, but
+// a matrix must be inverted on every call to Reconstruct.
+//
+// This replaces any size given to WithMatrixCacheSize, which keeps a
+// bounded number of matrices and is the default.
+func WithInversionTree(enabled bool) Option {
+	return func(o *options) {
+		if enabled {
+			o.matrixCacheSize = matrixCacheUnbounded
+		} else {
+			o.matrixCacheSize = 0
+		}
+	}
+}
+
+// WithFieldPoly will make the encoder use a Galois field generated
+// by the given polynomial, instead of the default 0x11d
+// (x^8 + x^4 + x^3 + x^2 + 1).
+// This is only needed to interoperate with implementations using
+// another field, and the output is not compatible with the default.
+// The polynomial must include the x^8 term and be primitive, which
+// means x must generate all non-zero elements, otherwise New returns
+// ErrInvalidFieldPoly. For example 0x11b is irreducible, but not
+// primitive, so it cannot be used.
+func WithFieldPoly(poly int) Option {
+	return func(o *options) {
+		o.fieldPoly = poly
+	}
+}
+
+// WithNibbleField will make the encoder use GF(2^4), generated by
+// x^4 + x + 1, instead of GF(2^8), which allows at most 16 data+parity
+// shards. This is only useful for interoperating with other GF(2^4)
+// implementations, as it is not faster than the default field.
+//
+// Every byte of a shard holds two 4 bit symbols: the low 4 bits and
+// the high 4 bits. The two halves are coded independently, so a byte
+// of parity holds the parity of the low halves in its low 4 bits, and
+// of the high halves in its high 4 bits. Shards keep the same layout
+// and API as with the default field, so any data can be given to
+// Split, and sizes need not be even.
+//
+// The output is not compatible with the default field, and New returns
+// ErrInvalidFieldPoly if WithFieldPoly is also given, and
+// ErrMaxShardNumNibble if there are more than 16 shards.
+func WithNibbleField() Option {
+	return func(o *options) {
+		o.nibbleField = true
+	}
+}
+
+// WithNonSystematic will make the encoder mix the data into every
+// shard, so no shard contains the original data verbatim.
+//
+// Encode, EncodeInto, EncodeBatch and the other functions encoding
+// from original data then overwrite the data shards with encoded
+// content, and the original data can only be recovered with DecodeData,
+// which solves the full system from any DataShards shards.
+// Verify, Reconstruct and their variants work on the encoded shards,
+// so Verify only checks that the shards are consistent with each other.
+// Functions that read the original data directly from the data shards,
+// like Join, must be called after DecodeData, and Update, EncodeSingle
+// and EncodeMmap return ErrNonSystematic.
+//
+// The encoding matrix is a Cauchy matrix, where every entry is
+// non-zero, and the output is not compatible with other modes.
+// The number of data shards plus the total number of shards must
+// not be more than 256.
+func WithNonSystematic() Option {
+	return func(o *options) {
+		o.nonSystematic = true
+	}
+}
+
+// WithLazyParity will make Reconstruct recreate all missing data
+// shards, but only the missing parity shards given as indexes.
+// Called without indexes, Reconstruct then functions as
+// ReconstructData, and other missing parity shards are left nil,
+// so the set must be completed before it is verified.
+// This saves the time to recreate parity that is not needed.
+// The stream encoder always recreates every shard it is asked to fill.
+func WithLazyParity() Option {
+	return func(o *options) {
+		o.lazyParity = true
+	}
+}
+
+// WithReconstructPlan will make the encoder call fn with a description
+// of how the shards were recreated, every time shards are
+// reconstructed, for instance to keep an audit log.
+//
+// fn is called by Reconstruct, ReconstructData and the other functions
+// recreating shards, in the calling goroutine before they return,
+// and is not called if nothing was recreated. The stream encoder calls
+// it for every block, while VerifyShards, DetectFailures and Correct,
+// which try several reconstructions to locate corrupted shards, never
+// call it.
+// The plan is a copy, so it may be kept.
+func WithReconstructPlan(fn func(ReconstructPlan)) Option {
+	return func(o *options) {
+		o.reconstructPlan = fn
+	}
+}
+
+// WithVerifyReconstruct will make Reconstruct, ReconstructData and
+// their variants call Verify when no shards are missing, and return
+// ErrCorruptionNotLocated if the shards are inconsistent, instead of
+// returning at once. This costs about as much as Encode on every
+// healthy read, so by default the shards are trusted as-is, and only
+// missing shards are recreated.
+// Shards that are recreated are never verified.
+func WithVerifyReconstruct() Option {
+	return func(o *options) {
+		o.verifyReconstruct = true
+	}
+}
+
+// WithReuseShards will make Reconstruct, ReconstructData and their
+// variants recreate a missing shard in place, if it has a length of 0,
+// but not nil, and enough capacity, so buffers can be reused without
+// allocating. By default a new shard is always allocated, so the spare
+// capacity of missing shards is never written.
+func WithReuseShards() Option {
+	return func(o *options) {
+		o.reuseShards = true
+	}
+}
+
+// WithReadAhead will make StreamEncoder.Reconstruct read up to the
+// given number of blocks from the valid streams in the background,
+// while the current block is reconstructed and written.
+// This overlaps reading with computation, which helps when the shards
+// are read from slow or remote storage.
+//
+// Every block holds up to 4MB of every shard, and blocks+1 blocks are
+// allocated, so the memory used grows with the number of blocks.
+// A value of 0 or less reads and reconstructs each block in turn,
+// which is the default.
+func WithReadAhead(blocks int) Option {
+	return func(o *options) {
+		if blocks < 0 {
+			blocks = 0
+		}
+		o.readAhead = blocks
+	}
+}
@@ -15,17 +15,28 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"os"
 	"runtime"
 	"sync"
 )
 
 // Encoder is an interface to encode Reed-Salomon parity sets for your data.
+//
+// All methods of an Encoder and its Extensions may be called
+// concurrently by multiple goroutines, except Reconfigure, which must
+// not run at the same time as any other method. Temporary buffers are
+// taken from a pool and the decode matrix cache is locked, so a single
+// Encoder can be shared, as long as the shard sets given to concurrent
+// calls do not overlap.
 type Encoder interface {
 	// Encodes parity for a set of data shards.
 	// Input is 'shards' containing data shards followed by parity shards.
 	// The number of shards must match the number given to New().
 	// Each shard is a byte array, and they must all be the same size.
+	// All data shards must be present, while nil parity shards are
+	// allocated.
 	// The parity shards will always be overwritten and the data shards
 	// will remain the same, so it is safe for you to read from the
 	// data shards while this is running.
@@ -46,7 +57,7 @@ type Encoder interface {
 	// You indicate that a shard is missing by setting it to nil.
 	//
 	// If there are too few shards to reconstruct the missing
-	// ones, ErrTooFewShards will be returned.
+	// ones, ErrTooManyFailures will be returned.
 	//
 	// The reconstructed shard set is complete, but integrity is not verified.
 	// Use the Verify function to check if data set is ok.
@@ -70,11 +81,252 @@ type Encoder interface {
 	//
 	// Only the data shards are considered.
 	// You must supply the exact output size you want.
-	// If there are to few shards given, ErrTooFewShards will be returned.
+	// If there are to few shards given, ErrShardCount will be returned.
 	// If the total data size is less than outSize, ErrShortData will be returned.
+	// A negative outSize returns ErrInvalidSize.
 	Join(dst io.Writer, shards [][]byte, outSize int) error
 }
 
+// Extensions extends Encoder with the functions of the encoders returned
+// by New beyond the basic interface. Every Encoder returned by New
+// implements it, so it can be accessed with a type assertion:
+//
+//	ext := enc.(reedsolomon.Extensions)
+//
+// Methods may be added to Extensions, so it should not be implemented
+// outside this package.
+type Extensions interface {
+	Encoder
+
+	// EncodeInto functions as Encode, but takes the data shards and
+	// the parity shards as separate slices.
+	// The parity shards must be allocated by the caller, with the
+	// same size as the data shards, and will always be overwritten.
+	EncodeInto(data, parity [][]byte) error
+
+	// EncodeSingle computes a single parity shard from the data shards,
+	// and stores it in dst. parityIndex is the index of the parity
+	// shard, from 0 to ParityShards-1.
+	EncodeSingle(data [][]byte, parityIndex int, dst []byte) error
+
+	// EncodeMmap encodes parity for shards stored in files, using
+	// memory mapped files. Each file is a shard, and all data files
+	// must have the same size.
+	// This is only supported on Unix like platforms.
+	EncodeMmap(dataFiles, parityFiles []*os.File) error
+
+	// EncodeBatch encodes parity for several shard sets in one call.
+	// Each element of objects is a shard set in the same format as
+	// Encode. Shard sizes may differ between sets.
+	EncodeBatch(objects [][][]byte) error
+
+	// Update parity shards after a single data shard has changed.
+	// Only the parity shards in 'shards' are used and updated, so
+	// the data shards may be nil.
+	// oldData and newData must be the previous and the new content
+	// of the data shard at dataShardIndex.
+	Update(shards [][]byte, dataShardIndex int, oldData, newData []byte) error
+
+	// ParityDelta returns the changes to XOR into each parity shard,
+	// when the data shards change from oldData to newData.
+	// Unchanged data shards may be nil in both.
+	ParityDelta(oldData, newData [][]byte) ([][]byte, error)
+
+	// EncodePartial adds the parity of the data shards listed in
+	// changed to parity. Giving the XOR of the old and new content of
+	// the changed shards updates the parity, and giving data shards
+	// to zeroed parity in several calls encodes them incrementally.
+	EncodePartial(data [][]byte, changed []int, parity [][]byte) error
+
+	// VerifyInto functions as Verify, but uses the supplied scratch
+	// buffers for the recomputed parity, so it does not allocate.
+	// There must be one scratch buffer for each parity shard, each
+	// at least the size of a shard.
+	VerifyInto(shards [][]byte, scratch [][]byte) (bool, error)
+
+	// VerifyShards functions as Verify, but also returns the indexes
+	// of the shards found to be inconsistent.
+	// A single corrupted shard can be identified if there are at least
+	// 2 parity shards. Otherwise the parity shards that do not match
+	// the data are returned.
+	VerifyShards(shards [][]byte) (ok bool, bad []int, err error)
+
+	// VerifyMany verifies several shard sets using a pool of workers,
+	// and returns the result for each set.
+	VerifyMany(stripes [][][]byte, workers int) ([]bool, error)
+
+	// ReconstructStats functions as Reconstruct, and also reports
+	// how many of the present shards were read to recreate the
+	// missing ones.
+	ReconstructStats(shards [][]byte, idxs ...int) (ReadStats, error)
+
+	// CanReconstruct returns true if the missing shards can be recreated
+	// when the shards marked in present are available.
+	CanReconstruct(present []bool) bool
+
+	// CanRepair returns true if the shards marked in failed can be
+	// recreated from the remaining shards.
+	CanRepair(failed []bool) bool
+
+	// DetectFailures returns the shards that are missing or found to
+	// be corrupted, which must be recreated before the set is used.
+	DetectFailures(shards [][]byte) (failed []bool, err error)
+
+	// Correct locates silently corrupted shards from the syndromes of
+	// every byte, up to (ParityShards-missing)/2 for every byte, and
+	// repairs them and returns their indexes.
+	Correct(shards [][]byte) (corrected []int, err error)
+
+	// MinimalSet returns the indexes of the DataShards shards that
+	// Reconstruct will read, when the shards marked in available
+	// are present.
+	MinimalSet(available []bool) ([]int, error)
+
+	// ReconstructLazy recreates the shards marked in failed, and calls
+	// fetch to read only the shards needed to do so.
+	ReconstructLazy(failed []bool, fetch func(idx int) ([]byte, error)) ([][]byte, error)
+
+	// ReconstructData will recreate any missing data shards, if possible.
+	//
+	// Given a list of shards, some of which contain data, fills in the
+	// data shards that don't have data.
+	//
+	// The length of the array must be equal to the total number of shards.
+	// You indicate that a shard is missing by setting it to nil.
+	//
+	// If there are too few shards to reconstruct the missing
+	// ones, ErrTooManyFailures will be returned.
+	//
+	// As the reconstructed shard set may contain missing parity shards,
+	// calling the Verify function is likely to fail.
+	ReconstructData(shards [][]byte) error
+
+	// DecodeData will store the original data in the data shards,
+	// recreating missing data shards first. This is only different
+	// from ReconstructData for a non-systematic encoder.
+	DecodeData(shards [][]byte) error
+
+	// Transcode converts a shard set to the scheme of dst, which may
+	// have another number of data and parity shards, by regrouping
+	// the data shards and encoding new parity.
+	Transcode(shards [][]byte, dst Encoder) ([][]byte, error)
+
+	// ReconstructSome functions as Reconstruct, but only recreates
+	// the missing shards marked in required. Other missing shards
+	// are left nil.
+	ReconstructSome(shards [][]byte, required []bool) error
+
+	// ReconstructIndexed functions as Reconstruct, but takes the
+	// present shards keyed by their index, and returns the complete
+	// shard set in order.
+	ReconstructIndexed(shards map[int][]byte) ([][]byte, error)
+
+	// Tags returns a tag for every shard in a complete shard set.
+	// The tags should be stored with the shards, so they can
+	// be given to ReconstructTagged.
+	Tags(shards [][]byte) ([]ShardTag, error)
+
+	// ReconstructTagged functions as Reconstruct, but will first check
+	// that every present shard matches the tag for its position.
+	// A ShardPositionError identifying the misplaced shards is
+	// returned if they don't.
+	ReconstructTagged(shards [][]byte, tags []ShardTag) error
+
+	// AttachChecksums appends a CRC32C checksum to every shard.
+	AttachChecksums(shards [][]byte) error
+
+	// ValidateChecksums checks and removes the checksums added by
+	// AttachChecksums, and returns the indexes of the shards that
+	// didn't match their checksum.
+	ValidateChecksums(shards [][]byte) ([]int, error)
+
+	// SplitData functions as Split, but only returns the data shards,
+	// so the caller can allocate the parity shards.
+	SplitData(data []byte) ([][]byte, error)
+
+	// JoinReordered functions as Join, but takes the shards in any
+	// order, with order[i] giving the canonical index of shards[i].
+	JoinReordered(dst io.Writer, shards [][]byte, order []int, outSize int) error
+
+	// JoinVerified functions as Join, and checks that the hash of the
+	// data written to dst, calculated with h, is wholeObjectHash.
+	JoinVerified(dst io.Writer, shards [][]byte, outSize int, wholeObjectHash []byte, h hash.Hash) error
+
+	// SplitPadded functions as Split, but also returns the number of
+	// zero bytes that were added after the data.
+	// The padding can be given to JoinTrim to recover the original data.
+	SplitPadded(data []byte) (shards [][]byte, padding int, err error)
+
+	// JoinTrim joins the data shards and writes them to dst,
+	// leaving out the padding added by SplitPadded.
+	JoinTrim(dst io.Writer, shards [][]byte, padding int) error
+
+	// SplitContiguous returns the shards stored back-to-back in a
+	// single buffer, each of shardSize bytes, without copying.
+	// The length of buf must be shardSize times the number of shards.
+	SplitContiguous(buf []byte, shardSize int) ([][]byte, error)
+
+	// EncodeContiguous functions as Encode on shards stored
+	// back-to-back in buf, and writes the parity in place.
+	EncodeContiguous(buf []byte, shardSize int) error
+
+	// ReconstructContiguous functions as Reconstruct on shards
+	// stored back-to-back in buf, and recreates the shards at
+	// the indexes in missing in place.
+	ReconstructContiguous(buf []byte, shardSize int, missing []int) error
+
+	// JoinContiguous writes the first outSize bytes of the data
+	// shards stored back-to-back in buf to dst.
+	JoinContiguous(dst io.Writer, buf []byte, shardSize int, outSize int) error
+
+	// EncodeStrided functions as Encode on shards stored in buf,
+	// with shard i starting at offset i*stride, and writes the
+	// parity in place.
+	EncodeStrided(buf []byte, shardSize, stride int) error
+
+	// AllocAligned allocates a complete set of shards of shardSize
+	// bytes, each aligned to a 64 byte boundary.
+	AllocAligned(shardSize int) [][]byte
+
+	// EncodeWithHeader splits data into encoded shards, with a header
+	// storing the length of data, so it can be joined without knowing
+	// the size.
+	EncodeWithHeader(data []byte) ([][]byte, error)
+
+	// JoinWithHeader writes the data encoded by EncodeWithHeader
+	// to dst, using the length stored in the header.
+	JoinWithHeader(dst io.Writer, shards [][]byte) error
+
+	// Reconfigure changes the number of data and parity shards,
+	// keeping the options given to New.
+	// It must not be called while the encoder is in use.
+	Reconfigure(dataShards, parityShards int) error
+
+	// Matrix returns a copy of the encoding matrix rows used to
+	// generate the parity shards.
+	// There is one row per parity shard, each with one coefficient
+	// per data shard.
+	Matrix() [][]byte
+
+	// Backend returns the name of the Galois field multiplication
+	// implementation used by the encoder.
+	// This is "avx2", "ssse3", "neon" or "pure-go".
+	// All implementations produce byte-for-byte identical output.
+	Backend() string
+
+	// StorageOverhead returns the number of parity bytes stored
+	// for every byte of data.
+	StorageOverhead() float64
+
+	// RepairDegree returns the number of shards read to recreate
+	// a single missing shard.
+	RepairDegree() int
+
+	// FaultTolerance returns the number of shards that can be lost
+	// while the data can still be recreated.
+	FaultTolerance() int
+}
+
 // reedSolomon contains a matrix for a specific
 // distribution of datashards and parity shards.
 // Construct if using New()
@@ -84,40 +336,144 @@ type reedSolomon struct {
 	Shards       int // Total number of shards. Calculated, and should not be modified.
 	m            matrix
 	parity       [][]byte
+	o            options
+	cache        *matrixCache // nil if disabled
+	mix, unmix   matrix       // nil unless non-systematic
+	single       matrix       // see buildSingleRecovery, nil without parity
 }
 
 // ErrInvShardNum will be returned by New, if you attempt to create
-// an Encoder where either data or parity shards is zero or less.
-var ErrInvShardNum = errors.New("cannot create Encoder with zero or less data/parity shards")
+// an Encoder with less than one data shard or less than zero
+// parity shards.
+var ErrInvShardNum = errors.New("cannot create Encoder with less than one data shard or less than zero parity shards")
 
 // ErrMaxShardNum will be returned by New, if you attempt to create
-// an Encoder where data and parity shards cannot be bigger than
-// Galois field GF(2^8) - 1.
-var ErrMaxShardNum = errors.New("cannot create Encoder with 255 or more data+parity shards")
+// an Encoder where data and parity shards are bigger than the order
+// of GF(2^8).
+var ErrMaxShardNum = errors.New("cannot create Encoder with more than 256 data+parity shards")
+
+// ErrInvalidKernelBlockSize is returned by New, if the size given to
+// WithKernelBlockSize is negative or not a multiple of 64.
+var ErrInvalidKernelBlockSize = errors.New("kernel block size must be a multiple of 64")
 
 // New creates a new encoder and initializes it to
 // the number of data shards and parity shards that
-// you want to use. You can reuse this encoder.
-// Note that the maximum number of data shards is 256.
-func New(dataShards, parityShards int) (Encoder, error) {
+// you want to use. You can reuse this encoder, also
+// from several goroutines at once.
+// Note that the maximum number of total shards is 256.
+// A code with zero parity shards is allowed, but cannot
+// reconstruct any missing shards.
+// If no options are supplied, default options are used.
+func New(dataShards, parityShards int, opts ...Option) (Encoder, error) {
 	r := reedSolomon{
-		DataShards:   dataShards,
-		ParityShards: parityShards,
-		Shards:       dataShards + parityShards,
+		o: defaultOptions,
+	}
+
+	for _, opt := range opts {
+		opt(&r.o)
+	}
+
+	if r.o.pool == nil {
+		r.o.pool = &sync.Pool{}
+	}
+	if r.o.kernelBlockSize < 0 || r.o.kernelBlockSize%64 != 0 {
+		return nil, ErrInvalidKernelBlockSize
+	}
+	if r.o.nibbleField {
+		if r.o.fieldPoly != 0 {
+			return nil, ErrInvalidFieldPoly
+		}
+		r.o.field = newNibbleField()
+	} else if r.o.fieldPoly != 0 && r.o.fieldPoly != 0x100+generatingPolynomial {
+		var err error
+		r.o.field, err = newGalField(r.o.fieldPoly)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if dataShards <= 0 || parityShards <= 0 {
-		return nil, ErrInvShardNum
+	err := r.Reconfigure(dataShards, parityShards)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Reconfigure changes the number of data and parity shards of the
+// encoder, keeping the options given to New.
+// The parameters are validated as in New, and if they are invalid
+// an error is returned and the encoder is unchanged.
+//
+// The parity slice is reused if possible, and cached decode
+// matrices are dropped.
+// Reconfigure must not be called while the encoder is in use by
+// other goroutines, and shards encoded with the previous
+// configuration can no longer be verified or reconstructed.
+func (r *reedSolomon) Reconfigure(dataShards, parityShards int) error {
+	if dataShards <= 0 || parityShards < 0 {
+		return ErrInvShardNum
+	}
+
+	maxShards, errMax := r.o.field.size(), ErrMaxShardNum
+	if r.o.nibbleField {
+		errMax = ErrMaxShardNumNibble
+	}
+	// Check each value first, so the sum cannot overflow.
+	if dataShards > maxShards || parityShards > maxShards || dataShards+parityShards > maxShards {
+		return errMax
 	}
 
-	if dataShards+parityShards > 255 {
-		return nil, ErrMaxShardNum
+	var m, mix, unmix matrix
+	var err error
+	if r.o.nonSystematic {
+		if 2*dataShards+parityShards > maxShards {
+			return errMax
+		}
+		m, mix, unmix, err = buildMatrixNonSystematic(dataShards, dataShards+parityShards, r.o.field)
+	} else if r.o.useCauchy {
+		m, err = buildMatrixCauchy(dataShards, dataShards+parityShards, r.o.field)
+	} else {
+		m, err = buildMatrix(dataShards, dataShards+parityShards, r.o.field)
+	}
+	if err != nil {
+		return err
 	}
 
+	r.DataShards = dataShards
+	r.ParityShards = parityShards
+	r.Shards = dataShards + parityShards
+	r.m = m
+	r.mix, r.unmix = mix, unmix
+	if cap(r.parity) >= parityShards {
+		r.parity = r.parity[:parityShards]
+	} else {
+		r.parity = make([][]byte, parityShards)
+	}
+	for i := range r.parity {
+		r.parity[i] = r.m[dataShards+i]
+	}
+	r.single = nil
+	if parityShards > 0 {
+		r.single = buildSingleRecovery(r.parity[0], r.o.field)
+	}
+	r.cache = nil
+	if r.o.matrixCacheSize != 0 {
+		r.cache = newMatrixCache(r.o.matrixCacheSize)
+	}
+	return nil
+}
+
+// buildMatrix creates the matrix to use for encoding, given the
+// number of data shards and the number of total shards.
+//
+// The top square of the matrix is guaranteed to be an identity
+// matrix, which means that the data shards are unchanged after
+// encoding.
+func buildMatrix(dataShards, totalShards int, f *galField) (matrix, error) {
 	// Start with a Vandermonde matrix.  This matrix would work,
 	// in theory, but doesn't have the property that the data
 	// shards are unchanged after encoding.
-	vm, err := vandermonde(r.Shards, dataShards)
+	vm, err := vandermonde(totalShards, dataShards, f)
 	if err != nil {
 		return nil, err
 	}
@@ -127,34 +483,190 @@ func New(dataShards, parityShards int) (Encoder, error) {
 	// preserve the property that any square subset of rows  is
 	// invertible.
 	top, _ := vm.SubMatrix(0, 0, dataShards, dataShards)
-	top, _ = top.Invert()
-	r.m, _ = vm.Multiply(top)
+	top, _ = top.invertWith(f)
+	return vm.multiplyWith(f, top)
+}
 
-	r.parity = make([][]byte, parityShards)
-	for i := range r.parity {
-		r.parity[i] = r.m[dataShards+i]
+// buildMatrixCauchy creates the matrix to use for encoding,
+// given the number of data shards and the number of total shards.
+//
+// The top square of the matrix is an identity matrix, and the
+// parity rows are a Cauchy matrix, where row r and column c
+// has the value 1/(r XOR c).
+// Any square subset of rows of this matrix is invertible.
+func buildMatrixCauchy(dataShards, totalShards int, f *galField) (matrix, error) {
+	result, err := newMatrix(totalShards, dataShards)
+	if err != nil {
+		return nil, err
+	}
+
+	for r, row := range result {
+		if r < dataShards {
+			result[r][r] = 1
+			continue
+		}
+		for c := range row {
+			result[r][c] = f.divide(1, byte(r^c))
+		}
+	}
+	return result, nil
+}
+
+// buildMatrixNonSystematic creates the matrices to use for
+// non-systematic encoding, given the number of data shards and
+// the number of total shards.
+//
+// The shards are encoded with a Cauchy matrix, where row r and column c
+// has the value 1/((dataShards+r) XOR c), so every entry is non-zero.
+// Since the encoded data shards are an invertible mix of the data,
+// the encoded shards are related by the systematic matrix m, which
+// is used to verify and reconstruct them.
+// mix encodes the data shards, and unmix is the inverse of mix.
+func buildMatrixNonSystematic(dataShards, totalShards int, f *galField) (m, mix, unmix matrix, err error) {
+	cm, err := newMatrix(totalShards, dataShards)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for r, row := range cm {
+		for c := range row {
+			row[c] = f.divide(1, byte((dataShards+r)^c))
+		}
+	}
+	mix, _ = cm.SubMatrix(0, 0, dataShards, dataShards)
+	unmix, err = mix.invertWith(f)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	m, err = cm.multiplyWith(f, unmix)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return m, mix, unmix, nil
+}
 
-	return &r, err
+// buildSingleRecovery creates the rows used to recreate a single
+// missing data shard from the other data shards and the first parity
+// shard, given the matrix row of that parity shard.
+//
+// Row i recreates data shard i, and takes the same inputs as the
+// other rows of the matrix, except that the first parity shard is
+// given in place of data shard i. Since parity = sum(row[c] * data[c]),
+// data[i] = (parity + sum(row[c] * data[c]), c != i) / row[i],
+// as addition and subtraction are the same.
+// Every entry of the parity rows is non-zero, so row[i] can always be
+// divided by.
+func buildSingleRecovery(parity []byte, f *galField) matrix {
+	m, _ := newMatrix(len(parity), len(parity))
+	for i, row := range m {
+		for c := range row {
+			if c == i {
+				row[c] = f.divide(1, parity[i])
+			} else {
+				row[c] = f.divide(parity[c], parity[i])
+			}
+		}
+	}
+	return m
 }
 
 // ErrTooFewShards is returned if too few shards where given to
 // Encode/Verify/Reconstruct. It will also be returned from Reconstruct
 // if there were too few shards to reconstruct the missing data.
+//
+// The functions return ErrShardCount or ErrTooManyFailures, which
+// both wrap ErrTooFewShards, so use errors.Is to check for it.
 var ErrTooFewShards = errors.New("too few shards given")
 
+// ErrShardCount is returned if the number of shards given
+// does not match the number of shards of the encoder.
+var ErrShardCount = fmt.Errorf("%w: shard count does not match encoder", ErrTooFewShards)
+
+// ErrTooManyFailures is returned from Reconstruct if there are
+// too few shards present to reconstruct the missing data.
+// The returned error is a TooManyFailuresError, which wraps it.
+var ErrTooManyFailures = fmt.Errorf("%w: too many shards missing to reconstruct", ErrTooFewShards)
+
+// TooManyFailuresError is returned from Reconstruct if more shards
+// are missing than there are parity shards. It is detected before
+// anything is calculated, and the shards are not modified.
+// Use errors.Is(err, ErrTooManyFailures) to check for it.
+type TooManyFailuresError struct {
+	Missing  []int // Indexes of the missing shards.
+	Required int   // Number of shards needed to reconstruct.
+}
+
+// tooManyFailures returns a TooManyFailuresError for shards.
+func tooManyFailures(shards [][]byte, required int) error {
+	var missing []int
+	for i, shard := range shards {
+		if len(shard) == 0 {
+			missing = append(missing, i)
+		}
+	}
+	return TooManyFailuresError{Missing: missing, Required: required}
+}
+
+// Error returns the error as a string
+func (e TooManyFailuresError) Error() string {
+	return fmt.Sprintf("%v: shards %v missing, need %d present", ErrTooManyFailures, e.Missing, e.Required)
+}
+
+// Unwrap returns ErrTooManyFailures.
+func (e TooManyFailuresError) Unwrap() error {
+	return ErrTooManyFailures
+}
+
+// ShardCountError is returned from Verify if the number of shards
+// does not match the encoder.
+// Use errors.Is(err, ErrShardCount) to check for it.
+type ShardCountError struct {
+	Got  int // Number of shards given.
+	Want int // Number of shards of the encoder.
+}
+
+// Error returns the error as a string
+func (e ShardCountError) Error() string {
+	return fmt.Sprintf("%v: got %d shards, want %d", ErrShardCount, e.Got, e.Want)
+}
+
+// Unwrap returns ErrShardCount.
+func (e ShardCountError) Unwrap() error {
+	return ErrShardCount
+}
+
+// ShardSizeError is returned from Verify if a shard does not have
+// the same size as the first non-empty shard.
+// Use errors.Is(err, ErrShardSize) to check for it.
+type ShardSizeError struct {
+	Shard int // Index of the first shard with a different size.
+	Size  int // Size of that shard.
+	Want  int // Size of the first non-empty shard.
+}
+
+// Error returns the error as a string
+func (e ShardSizeError) Error() string {
+	return fmt.Sprintf("%v: shard %d has %d bytes, want %d", ErrShardSize, e.Shard, e.Size, e.Want)
+}
+
+// Unwrap returns ErrShardSize.
+func (e ShardSizeError) Unwrap() error {
+	return ErrShardSize
+}
+
 // Encodes parity for a set of data shards.
 // An array 'shards' containing data shards followed by parity shards.
 // The number of shards must match the number given to New.
 // Each shard is a byte array, and they must all be the same size.
+// If a data shard is nil or empty, ErrShardNoData is returned. Parity
+// shards that are nil are allocated, and stored in 'shards'.
 // The parity shards will always be overwritten and the data shards
 // will remain the same.
 func (r reedSolomon) Encode(shards [][]byte) error {
 	if len(shards) != r.Shards {
-		return ErrTooFewShards
+		return ErrShardCount
 	}
 
-	err := checkShards(shards, false)
+	err := r.checkEncodeShards(shards)
 	if err != nil {
 		return err
 	}
@@ -163,19 +675,354 @@ func (r reedSolomon) Encode(shards [][]byte) error {
 	output := shards[r.DataShards:]
 
 	// Do the coding.
+	r.mixData(shards[:r.DataShards], len(shards[0]))
 	r.codeSomeShards(r.parity, shards[0:r.DataShards], output, r.ParityShards, len(shards[0]))
 	return nil
 }
 
+// checkEncodeShards checks the shards given to Encode.
+// All data shards must be present and have the same size, otherwise
+// ErrShardNoData or ErrShardSize is returned. Parity shards must have
+// the same size, or be nil, in which case they are allocated.
+func (r reedSolomon) checkEncodeShards(shards [][]byte) error {
+	size := len(shards[0])
+	for _, shard := range shards[:r.DataShards] {
+		if len(shard) == 0 {
+			return ErrShardNoData
+		}
+		if len(shard) != size {
+			return ErrShardSize
+		}
+	}
+	var missing int
+	for _, shard := range shards[r.DataShards:] {
+		if len(shard) == 0 {
+			missing++
+		} else if len(shard) != size {
+			return ErrShardSize
+		}
+	}
+	if missing == 0 {
+		return nil
+	}
+	parity := AllocAligned(missing, size)
+	for i := r.DataShards; i < r.Shards; i++ {
+		if len(shards[i]) == 0 {
+			shards[i], parity = parity[0], parity[1:]
+		}
+	}
+	return nil
+}
+
+// EncodeInto functions as Encode, but takes the data shards and
+// the parity shards as separate slices.
+// The number of data and parity shards must match the numbers given
+// to New, and all shards must be the same size.
+// Parity buffers are not allocated, so they can be reused between
+// calls.
+func (r reedSolomon) EncodeInto(data, parity [][]byte) error {
+	if len(data) != r.DataShards || len(parity) != r.ParityShards {
+		return ErrShardCount
+	}
+	err := checkShards(data, false)
+	if err != nil {
+		return err
+	}
+	size := len(data[0])
+	for _, p := range parity {
+		if len(p) != size {
+			return ErrShardSize
+		}
+	}
+	r.mixData(data, size)
+	r.codeSomeShards(r.parity, data, parity, r.ParityShards, size)
+	return nil
+}
+
+// EncodeSingle computes only the parity shard at parityIndex, from 0
+// to ParityShards-1, and stores it in dst.
+// This is cheaper than Reconstruct, if a single parity shard must be
+// recreated and all data shards are present.
+// The number of data shards must match the number given to New, and
+// dst must have the same size as the data shards.
+func (r reedSolomon) EncodeSingle(data [][]byte, parityIndex int, dst []byte) error {
+	if r.mix != nil {
+		return ErrNonSystematic
+	}
+	if len(data) != r.DataShards {
+		return ErrShardCount
+	}
+	if parityIndex < 0 || parityIndex >= r.ParityShards {
+		return ErrInvalidShardIndex
+	}
+	err := checkShards(data, false)
+	if err != nil {
+		return err
+	}
+	if len(dst) != len(data[0]) {
+		return ErrShardSize
+	}
+	r.codeSomeShards(r.parity[parityIndex:parityIndex+1], data, [][]byte{dst}, 1, len(dst))
+	return nil
+}
+
+// EncodeBatch encodes parity for several shard sets in one call.
+// Each element of objects is a shard set in the same format as Encode,
+// so nil parity shards are allocated.
+// All sets are validated before any parity is written.
+//
+// Instead of splitting each set into several goroutines, complete
+// sets are distributed over the available CPUs, which reduces the
+// overhead when encoding many small sets.
+func (r reedSolomon) EncodeBatch(objects [][][]byte) error {
+	for _, shards := range objects {
+		if len(shards) != r.Shards {
+			return ErrShardCount
+		}
+		err := r.checkEncodeShards(shards)
+		if err != nil {
+			return err
+		}
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(objects) {
+		workers = len(objects)
+	}
+	if workers <= 1 {
+		for _, shards := range objects {
+			r.mixData(shards[:r.DataShards], len(shards[0]))
+			r.codeSomeShards(r.parity, shards[:r.DataShards], shards[r.DataShards:], r.ParityShards, len(shards[0]))
+		}
+		return nil
+	}
+
+	tasks := make([]func(), workers)
+	for w := range tasks {
+		w := w
+		tasks[w] = func() {
+			for i := w; i < len(objects); i += workers {
+				shards := objects[i]
+				r.mixData(shards[:r.DataShards], len(shards[0]))
+				r.codeSomeShardsS(r.parity, shards[:r.DataShards], shards[r.DataShards:], r.ParityShards)
+			}
+		}
+	}
+	r.o.run(tasks)
+	return nil
+}
+
+// ErrNonSystematic is returned by functions that need the original
+// data in the data shards, when the encoder is non-systematic.
+var ErrNonSystematic = errors.New("not supported by a non-systematic encoder")
+
+// mixData replaces the data shards with the encoded data shards,
+// if the encoder is non-systematic. Otherwise nothing is done.
+func (r reedSolomon) mixData(data [][]byte, size int) {
+	if r.mix == nil {
+		return
+	}
+	bufs := make([]*[]byte, len(data))
+	in := make([][]byte, len(data))
+	for i, shard := range data {
+		bufs[i] = r.getBuffer(size)
+		in[i] = *bufs[i]
+		copy(in[i], shard)
+	}
+	r.codeSomeShards(r.mix, in, data, r.DataShards, size)
+	for _, buf := range bufs {
+		r.o.pool.Put(buf)
+	}
+}
+
+// ErrInvalidShardIndex is returned if a shard index is out of range.
+var ErrInvalidShardIndex = errors.New("shard index out of range")
+
+// ErrMmapNotSupported is returned by EncodeMmap on platforms
+// where memory mapped files are not supported.
+var ErrMmapNotSupported = errors.New("memory mapped files are not supported on this platform")
+
+// Update parity shards after a single data shard has changed.
+//
+// Since the code is linear, the parity can be updated using only
+// the difference between the old and new content of the data shard,
+// without reading any of the other data shards.
+// Only the parity shards in 'shards' are used and updated, so
+// the data shards may be nil. The data shard at dataShardIndex
+// is not modified.
+// All parity shards, oldData and newData must be the same size.
+func (r reedSolomon) Update(shards [][]byte, dataShardIndex int, oldData, newData []byte) error {
+	if r.mix != nil {
+		return ErrNonSystematic
+	}
+	if len(shards) != r.Shards {
+		return ErrShardCount
+	}
+	if dataShardIndex < 0 || dataShardIndex >= r.DataShards {
+		return ErrInvalidShardIndex
+	}
+	if r.ParityShards == 0 {
+		return nil
+	}
+	parity := shards[r.DataShards:]
+	err := checkShards(parity, false)
+	if err != nil {
+		return err
+	}
+	size := len(parity[0])
+	if len(oldData) != size || len(newData) != size {
+		return ErrShardSize
+	}
+
+	buf := r.getBuffer(size)
+	delta := *buf
+	for i := range delta {
+		delta[i] = oldData[i] ^ newData[i]
+	}
+	for iRow, out := range parity {
+		galMulSliceXor(r.parity[iRow][dataShardIndex], delta, out, &r.o)
+	}
+	r.o.pool.Put(buf)
+	return nil
+}
+
+// ParityDelta returns the difference between the parity of oldData and
+// the parity of newData, one slice per parity shard.
+// XOR'ing each slice into the corresponding parity shard gives the
+// parity of newData, so parity can be updated without rewriting the
+// unchanged parts or reading the unchanged data shards.
+//
+// oldData and newData must both contain DataShards shards. A data shard
+// that has not changed may be nil in both, otherwise both must be
+// present and all present shards must be the same size.
+// If no shards have changed, the deltas are all zero.
+func (r reedSolomon) ParityDelta(oldData, newData [][]byte) ([][]byte, error) {
+	if r.mix != nil {
+		return nil, ErrNonSystematic
+	}
+	if len(oldData) != r.DataShards || len(newData) != r.DataShards {
+		return nil, ErrShardCount
+	}
+	size := -1
+	for i := range oldData {
+		if oldData[i] == nil && newData[i] == nil {
+			continue
+		}
+		if size < 0 {
+			size = len(oldData[i])
+		}
+		if len(oldData[i]) != size || len(newData[i]) != size {
+			return nil, ErrShardSize
+		}
+	}
+	if size <= 0 {
+		return nil, ErrShardNoData
+	}
+
+	deltas := AllocAligned(r.ParityShards, size)
+	buf := r.getBuffer(size)
+	delta := *buf
+	for i := range oldData {
+		if oldData[i] == nil {
+			continue
+		}
+		for j := range delta {
+			delta[j] = oldData[i][j] ^ newData[i][j]
+		}
+		for iRow, out := range deltas {
+			galMulSliceXor(r.parity[iRow][i], delta, out, &r.o)
+		}
+	}
+	r.o.pool.Put(buf)
+	return deltas, nil
+}
+
+// EncodePartial adds the contribution of the data shards listed in
+// changed to the parity shards, so only those data shards are read.
+//
+// Since the code is linear, this can be used in two ways:
+//
+//   - To update the parity after some data shards changed, give the
+//     XOR of the old and new content of each changed shard in data.
+//     This functions as calling Update for every changed shard.
+//   - To encode data shards as they become available, start with
+//     zeroed parity shards, and give the data shards in one or more
+//     calls. Once every data shard has been given exactly once, the
+//     parity is the same as computed by Encode.
+//
+// data must have DataShards entries, and only those listed in changed
+// are used, so the others may be nil. changed must not contain an
+// index more than once, otherwise ErrInvalidShardIndex is returned.
+// The listed data shards and all parity shards must be the same size.
+func (r reedSolomon) EncodePartial(data [][]byte, changed []int, parity [][]byte) error {
+	if r.mix != nil {
+		return ErrNonSystematic
+	}
+	if len(data) != r.DataShards || len(parity) != r.ParityShards {
+		return ErrShardCount
+	}
+	seen := make([]bool, r.DataShards)
+	for _, i := range changed {
+		if i < 0 || i >= r.DataShards || seen[i] {
+			return ErrInvalidShardIndex
+		}
+		seen[i] = true
+	}
+	if len(changed) == 0 || r.ParityShards == 0 {
+		return nil
+	}
+	size := len(data[changed[0]])
+	if size == 0 {
+		return ErrShardNoData
+	}
+	for _, i := range changed {
+		if len(data[i]) != size {
+			return ErrShardSize
+		}
+	}
+	for _, p := range parity {
+		if len(p) != size {
+			return ErrShardSize
+		}
+	}
+	for _, i := range changed {
+		for iRow, out := range parity {
+			galMulSliceXor(r.parity[iRow][i], data[i], out, &r.o)
+		}
+	}
+	return nil
+}
+
+// getBuffer returns a temporary buffer of the given size from
+// the buffer pool. The content of the buffer is undefined.
+// The buffer should be returned to r.o.pool after use.
+func (r reedSolomon) getBuffer(size int) *[]byte {
+	if buf, ok := r.o.pool.Get().(*[]byte); ok && cap(*buf) >= size {
+		*buf = (*buf)[:size]
+		return buf
+	}
+	buf := make([]byte, size)
+	return &buf
+}
+
 // Verify returns true if the parity shards contain the right data.
 // The data is the same format as Encode. No data is modified.
+//
+// If the number of shards does not match the encoder, a
+// ShardCountError is returned, and if a shard has another size
+// than the rest, a ShardSizeError naming the shard is returned.
 func (r reedSolomon) Verify(shards [][]byte) (bool, error) {
 	if len(shards) != r.Shards {
-		return false, ErrTooFewShards
+		return false, ShardCountError{Got: len(shards), Want: r.Shards}
 	}
-	err := checkShards(shards, false)
-	if err != nil {
-		return false, err
+	size := shardSize(shards)
+	if size == 0 {
+		return false, ErrShardNoData
+	}
+	for i, shard := range shards {
+		if len(shard) != size {
+			return false, ShardSizeError{Shard: i, Size: len(shard), Want: size}
+		}
 	}
 
 	// Slice of buffers being checked.
@@ -185,6 +1032,155 @@ func (r reedSolomon) Verify(shards [][]byte) (bool, error) {
 	return r.checkSomeShards(r.parity, shards[0:r.DataShards], toCheck, r.ParityShards, len(shards[0])), nil
 }
 
+// VerifyInto functions as Verify, but uses the supplied scratch
+// buffers for the recomputed parity, so it does not allocate.
+//
+// There must be exactly ParityShards scratch buffers, and each must
+// be at least the size of a shard, otherwise ErrShardSize is returned.
+// The content of the scratch buffers is overwritten.
+// The calculation is done in the calling goroutine.
+func (r reedSolomon) VerifyInto(shards [][]byte, scratch [][]byte) (bool, error) {
+	if len(shards) != r.Shards {
+		return false, ErrShardCount
+	}
+	err := checkShards(shards, false)
+	if err != nil {
+		return false, err
+	}
+	size := len(shards[0])
+	if len(scratch) != r.ParityShards {
+		return false, ErrShardCount
+	}
+	for _, s := range scratch {
+		if len(s) < size {
+			return false, ErrShardSize
+		}
+	}
+
+	for c := 0; c < r.DataShards; c++ {
+		in := shards[c]
+		for iRow := 0; iRow < r.ParityShards; iRow++ {
+			if c == 0 {
+				galMulSlice(r.parity[iRow][c], in, scratch[iRow][:size], &r.o)
+			} else {
+				galMulSliceXor(r.parity[iRow][c], in, scratch[iRow][:size], &r.o)
+			}
+		}
+	}
+	for i, calc := range scratch {
+		if !bytes.Equal(calc[:size], shards[r.DataShards+i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// VerifyMany verifies several shard sets, and returns whether the
+// parity is correct for each of them. Each element of stripes is a
+// shard set in the same format as Verify. No data is modified.
+//
+// The sets are distributed over the given number of workers, and each
+// worker reuses its scratch buffers, so this is cheaper than calling
+// Verify from several goroutines. If workers is 0 or less, GOMAXPROCS
+// workers are used.
+// All sets are checked for errors before verification starts, and an
+// error identifying the first invalid set is returned if any is found.
+func (r reedSolomon) VerifyMany(stripes [][][]byte, workers int) ([]bool, error) {
+	maxSize := 0
+	for i, shards := range stripes {
+		if len(shards) != r.Shards {
+			return nil, fmt.Errorf("stripe %d: %w", i, ErrShardCount)
+		}
+		err := checkShards(shards, false)
+		if err != nil {
+			return nil, fmt.Errorf("stripe %d: %w", i, err)
+		}
+		if len(shards[0]) > maxSize {
+			maxSize = len(shards[0])
+		}
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(stripes) {
+		workers = len(stripes)
+	}
+
+	result := make([]bool, len(stripes))
+	tasks := make([]func(), workers)
+	for w := range tasks {
+		w := w
+		tasks[w] = func() {
+			scratch := createSlice(r.ParityShards, maxSize)
+			for i := w; i < len(stripes); i += workers {
+				// Only shard sizes have been validated, so this cannot fail.
+				result[i], _ = r.VerifyInto(stripes[i], scratch)
+			}
+		}
+	}
+	r.o.run(tasks)
+	return result, nil
+}
+
+// VerifyShards returns true if the parity shards contain the right
+// data, and otherwise the indexes of the shards that are inconsistent.
+// The data is the same format as Encode. No data is modified.
+//
+// If the mismatch can be explained by a single corrupted shard, only
+// that shard is returned. This requires at least 2 parity shards, as
+// with 1 parity shard every shard is an equally likely candidate.
+// If more shards are corrupted, they cannot be located reliably.
+// In that case the parity shards that do not match the parity
+// calculated from the data shards are returned, and it is up to the
+// caller to decide which shards to discard.
+func (r reedSolomon) VerifyShards(shards [][]byte) (bool, []int, error) {
+	if len(shards) != r.Shards {
+		return false, nil, ErrShardCount
+	}
+	err := checkShards(shards, false)
+	if err != nil {
+		return false, nil, err
+	}
+	size := len(shards[0])
+	calc := createSlice(r.ParityShards, size)
+	r.codeSomeShards(r.parity, shards[:r.DataShards], calc, r.ParityShards, size)
+	var bad []int
+	for i := range calc {
+		if !bytes.Equal(calc[i], shards[r.DataShards+i]) {
+			bad = append(bad, r.DataShards+i)
+		}
+	}
+	if len(bad) == 0 {
+		return true, nil, nil
+	}
+	if r.ParityShards < 2 {
+		return false, bad, nil
+	}
+
+	// Try to find a single shard that explains the mismatch,
+	// by treating each shard as missing.
+	// These reconstructions are not reported to WithReconstructPlan.
+	r.o.reconstructPlan = nil
+	r.o.verifyReconstruct = false
+	test := make([][]byte, r.Shards)
+	for i := range shards {
+		copy(test, shards)
+		test[i] = nil
+		err = r.reconstruct(test, false)
+		if err != nil {
+			return false, nil, err
+		}
+		ok, err := r.Verify(test)
+		if err != nil {
+			return false, nil, err
+		}
+		if ok {
+			return false, []int{i}, nil
+		}
+	}
+	return false, bad, nil
+}
+
 // Multiplies a subset of rows from a coding matrix by a full set of
 // input shards to produce some output shards.
 // 'matrixRows' is The rows from the matrix to use.
@@ -199,13 +1195,39 @@ func (r reedSolomon) codeSomeShards(matrixRows, inputs, outputs [][]byte, output
 		r.codeSomeShardsP(matrixRows, inputs, outputs, outputCount, byteCount)
 		return
 	}
-	for c := 0; c < r.DataShards; c++ {
-		in := inputs[c]
-		for iRow := 0; iRow < outputCount; iRow++ {
-			if c == 0 {
-				galMulSlice(matrixRows[iRow][c], in, outputs[iRow])
-			} else {
-				galMulSliceXor(matrixRows[iRow][c], in, outputs[iRow])
+	r.codeSomeShardsS(matrixRows, inputs, outputs, outputCount)
+}
+
+// codeSomeShardsS performs the same as codeSomeShards,
+// but always in the calling goroutine.
+func (r reedSolomon) codeSomeShardsS(matrixRows, inputs, outputs [][]byte, outputCount int) {
+	r.codeRange(matrixRows, inputs, outputs, outputCount, 0, len(inputs[0]))
+}
+
+// codeRange performs the same as codeSomeShardsS, but only for the
+// bytes from start to stop, and in blocks of the kernel block size.
+func (r reedSolomon) codeRange(matrixRows, inputs, outputs [][]byte, outputCount, start, stop int) {
+	if outputCount == 0 {
+		// The inputs may not be present.
+		return
+	}
+	block := r.o.kernelBlockSize
+	if block == 0 {
+		block = stop - start
+	}
+	for ; start < stop; start += block {
+		end := start + block
+		if end > stop {
+			end = stop
+		}
+		for c := 0; c < r.DataShards; c++ {
+			in := inputs[c][start:end]
+			for iRow := 0; iRow < outputCount; iRow++ {
+				if c == 0 {
+					galMulSlice(matrixRows[iRow][c], in, outputs[iRow][start:end], &r.o)
+				} else {
+					galMulSliceXor(matrixRows[iRow][c], in, outputs[iRow][start:end], &r.o)
+				}
 			}
 		}
 	}
@@ -219,33 +1241,21 @@ const (
 // Perform the same as codeSomeShards, but split the workload into
 // several goroutines.
 func (r reedSolomon) codeSomeShardsP(matrixRows, inputs, outputs [][]byte, outputCount, byteCount int) {
-	var wg sync.WaitGroup
 	do := byteCount / maxGoroutines
 	if do < minSplitSize {
 		do = minSplitSize
 	}
-	start := 0
-	for start < byteCount {
-		if start+do > byteCount {
-			do = byteCount - start
+	var tasks []func()
+	for start := 0; start < byteCount; start += do {
+		start, stop := start, start+do
+		if stop > byteCount {
+			stop = byteCount
 		}
-		wg.Add(1)
-		go func(start, stop int) {
-			for c := 0; c < r.DataShards; c++ {
-				in := inputs[c]
-				for iRow := 0; iRow < outputCount; iRow++ {
-					if c == 0 {
-						galMulSlice(matrixRows[iRow][c], in[start:stop], outputs[iRow][start:stop])
-					} else {
-						galMulSliceXor(matrixRows[iRow][c], in[start:stop], outputs[iRow][start:stop])
-					}
-				}
-			}
-			wg.Done()
-		}(start, start+do)
-		start += do
+		tasks = append(tasks, func() {
+			r.codeRange(matrixRows, inputs, outputs, outputCount, start, stop)
+		})
 	}
-	wg.Wait()
+	r.o.run(tasks)
 }
 
 // checkSomeShards is mostly the same as codeSomeShards,
@@ -253,24 +1263,24 @@ func (r reedSolomon) codeSomeShardsP(matrixRows, inputs, outputs [][]byte, outpu
 // as soon as a difference is found.
 func (r reedSolomon) checkSomeShards(matrixRows, inputs, toCheck [][]byte, outputCount, byteCount int) bool {
 	same := true
-	var mu sync.RWMutex // For above
-
-	var wg sync.WaitGroup
-	do := byteCount / maxGoroutines
-	if do < minSplitSize {
-		do = minSplitSize
+	var mu sync.RWMutex // For above
+
+	step := byteCount / maxGoroutines
+	if step < minSplitSize {
+		step = minSplitSize
 	}
-	start := 0
-	for start < byteCount {
+	var tasks []func()
+	for start := 0; start < byteCount; start += step {
+		start, do := start, step
 		if start+do > byteCount {
 			do = byteCount - start
 		}
-		wg.Add(1)
-		go func(start, do int) {
-			defer wg.Done()
+		tasks = append(tasks, func() {
+			buf := r.getBuffer(do * len(toCheck))
+			defer r.o.pool.Put(buf)
 			outputs := make([][]byte, len(toCheck))
 			for i := range outputs {
-				outputs[i] = make([]byte, do)
+				outputs[i] = (*buf)[i*do : (i+1)*do]
 			}
 			for c := 0; c < r.DataShards; c++ {
 				mu.RLock()
@@ -281,7 +1291,11 @@ func (r reedSolomon) checkSomeShards(matrixRows, inputs, toCheck [][]byte, outpu
 				mu.RUnlock()
 				in := inputs[c][start : start+do]
 				for iRow := 0; iRow < outputCount; iRow++ {
-					galMulSliceXor(matrixRows[iRow][c], in, outputs[iRow])
+					if c == 0 {
+						galMulSlice(matrixRows[iRow][c], in, outputs[iRow], &r.o)
+					} else {
+						galMulSliceXor(matrixRows[iRow][c], in, outputs[iRow], &r.o)
+					}
 				}
 			}
 
@@ -293,10 +1307,9 @@ func (r reedSolomon) checkSomeShards(matrixRows, inputs, toCheck [][]byte, outpu
 					return
 				}
 			}
-		}(start, do)
-		start += do
+		})
 	}
-	wg.Wait()
+	r.o.run(tasks)
 	return same
 }
 
@@ -347,22 +1360,539 @@ func shardSize(shards [][]byte) int {
 // You indicate that a shard is missing by setting it to nil.
 //
 // If there are too few shards to reconstruct the missing
-// ones, ErrTooFewShards will be returned.
+// ones, ErrTooManyFailures will be returned.
+//
+// If all requested shards are present, nothing is allocated or
+// computed, and the input is trusted as-is. With WithVerifyReconstruct
+// the shards are verified if none are missing, and
+// ErrCorruptionNotLocated is returned if they are inconsistent.
+// Missing shards are allocated, unless WithReuseShards is used.
+// If exactly one data shard is missing, it is recreated from the other
+// data shards and the first parity shard, if present, without
+// inverting a matrix.
+//
+// If WithLazyParity is used, all missing data shards are recreated,
+// but missing parity shards are only recreated if listed in idxs.
 //
 // The reconstructed shard set is complete, but integrity is not verified.
 // Use the Verify function to check if data set is ok.
 func (r reedSolomon) Reconstruct(shards [][]byte, idxs ...int) error {
-	return r.reconstruct(shards, idxs...)
+	if r.o.lazyParity {
+		// Every data shard is required, but only the listed parity.
+		required := make([]int, 0, r.DataShards+len(idxs))
+		for i := 0; i < r.DataShards; i++ {
+			required = append(required, i)
+		}
+		for _, idx := range idxs {
+			if idx >= r.DataShards || idx < 0 {
+				required = append(required, idx)
+			}
+		}
+		return r.reconstruct(shards, false, required...)
+	}
+	return r.reconstruct(shards, false, idxs...)
+}
+
+// ReconstructPlan describes how missing shards were recreated, and is
+// given to the function set by WithReconstructPlan.
+//
+// Every recreated shard is the sum of the source shards multiplied by
+// the coefficients of its row of Matrix, in the Galois field of the
+// encoder, so the plan can be checked independently of the encoder.
+type ReconstructPlan struct {
+	Sources []int    // Indexes of the shards read, in increasing order.
+	Targets []int    // Indexes of the shards recreated, in increasing order.
+	Matrix  [][]byte // Row i holds a coefficient per source for Targets[i].
+}
+
+// reportPlan gives the plan of a reconstruction to the function set
+// by WithReconstructPlan.
+// missing are the indexes of the shards missing before the
+// reconstruction, and every recreated data shard i is row i of decode
+// multiplied with sources. If decode is nil, no data shards were
+// missing, and the sources are the data shards.
+func (r reedSolomon) reportPlan(shards [][]byte, missing, sources []int, decode matrix) {
+	plan := ReconstructPlan{Sources: append([]int(nil), sources...)}
+	for _, idx := range missing {
+		if len(shards[idx]) == 0 {
+			// Not requested.
+			continue
+		}
+		var row []byte
+		switch {
+		case idx < r.DataShards:
+			row = append([]byte(nil), decode[idx]...)
+		case decode == nil:
+			row = append([]byte(nil), r.parity[idx-r.DataShards]...)
+		default:
+			// The parity is calculated from the data shards, so
+			// combine its row with the rows giving the data shards.
+			row = make([]byte, len(sources))
+			for j, c := range r.parity[idx-r.DataShards] {
+				for k, d := range decode[j] {
+					row[k] ^= r.o.field.multiply(c, d)
+				}
+			}
+		}
+		plan.Targets = append(plan.Targets, idx)
+		plan.Matrix = append(plan.Matrix, row)
+	}
+	if len(plan.Targets) > 0 {
+		r.o.reconstructPlan(plan)
+	}
+}
+
+// singlePlan returns the sources and decode matrix, as given to
+// reportPlan, when data shard missing is recreated by reconstructSingle.
+func (r reedSolomon) singlePlan(missing int) ([]int, matrix) {
+	sources := make([]int, 0, r.DataShards)
+	for i := 0; i <= r.DataShards; i++ {
+		if i != missing {
+			sources = append(sources, i)
+		}
+	}
+	decode, _ := newMatrix(r.DataShards, r.DataShards)
+	for k, idx := range sources {
+		if idx == r.DataShards {
+			decode[missing][k] = r.single[missing][missing]
+			continue
+		}
+		decode[idx][k] = 1
+		decode[missing][k] = r.single[missing][idx]
+	}
+	return sources, decode
+}
+
+// ReadStats reports the input consumed by ReconstructStats.
+type ReadStats struct {
+	ShardsRead int // Number of present shards read.
+	BytesRead  int // Number of bytes read from present shards.
+}
+
+// ReconstructStats functions as Reconstruct, and also reports how many
+// of the present shards were read to recreate the missing shards.
+//
+// No more than DataShards shards are ever read, and when several
+// shards are missing, the lowest indexed present shards are used.
+// Shards beyond these do not need to be fetched from storage.
+// If nothing needs to be recreated, no shards are read.
+// On error, the returned ReadStats is zero.
+func (r reedSolomon) ReconstructStats(shards [][]byte, idxs ...int) (ReadStats, error) {
+	// Determine what will be read before the missing shards are filled in.
+	var st ReadStats
+	needed := false
+	for i, shard := range shards {
+		if len(shard) == 0 && (len(idxs) == 0 || contains(idxs, i)) {
+			needed = true
+		}
+	}
+	if needed {
+		for _, shard := range shards {
+			if len(shard) != 0 && st.ShardsRead < r.DataShards {
+				st.ShardsRead++
+				st.BytesRead += len(shard)
+			}
+		}
+	}
+	err := r.reconstruct(shards, false, idxs...)
+	if err != nil {
+		return ReadStats{}, err
+	}
+	return st, nil
+}
+
+// CanReconstruct returns true if Reconstruct will succeed when the
+// shards marked in present are available, without looking at any data.
+// Since the code is maximum distance separable, any DataShards shards
+// are sufficient.
+// The length of present must be equal to Shards, otherwise false is
+// returned.
+func (r reedSolomon) CanReconstruct(present []bool) bool {
+	if len(present) != r.Shards {
+		return false
+	}
+	n := 0
+	for _, p := range present {
+		if p {
+			n++
+		}
+	}
+	return n >= r.DataShards
+}
+
+// CanRepair returns true if the shards marked in failed can be
+// recreated from the remaining shards, which is the case if no more
+// than ParityShards shards have failed.
+// The length of failed must be equal to Shards, otherwise false is
+// returned.
+func (r reedSolomon) CanRepair(failed []bool) bool {
+	if len(failed) != r.Shards {
+		return false
+	}
+	present := make([]bool, len(failed))
+	for i, f := range failed {
+		present[i] = !f
+	}
+	return r.CanReconstruct(present)
+}
+
+// ErrCorruptionNotLocated is returned by DetectFailures and Correct if
+// the shards are inconsistent, but the corrupted shards cannot be
+// identified, and by Reconstruct with WithVerifyReconstruct if no
+// shards are missing and they are inconsistent.
+var ErrCorruptionNotLocated = errors.New("shards are inconsistent, but the corrupted shards cannot be located")
+
+// DetectFailures returns a slice marking the shards that are missing
+// or corrupted, which can be given to CanRepair. The shards marked
+// should be set to nil and recreated with Reconstruct.
+// No data is modified.
+//
+// A present shard is reported as corrupted if the set is inconsistent,
+// and becomes consistent when that shard alone is left out.
+// This requires two more present shards than DataShards: with exactly
+// DataShards present, any content is consistent, so corruption cannot
+// be detected and only the missing shards are marked. If the shards
+// are inconsistent and no single shard explains it,
+// ErrCorruptionNotLocated is returned together with the missing shards.
+// Shards stored with checksums can be checked independently with
+// ValidateChecksums, which can also detect more corrupted shards.
+//
+// If fewer than DataShards shards are present, a TooManyFailuresError
+// is returned.
+func (r reedSolomon) DetectFailures(shards [][]byte) ([]bool, error) {
+	if len(shards) != r.Shards {
+		return nil, ErrShardCount
+	}
+	err := checkShards(shards, true)
+	if err != nil {
+		return nil, err
+	}
+	failed := make([]bool, r.Shards)
+	present := 0
+	for i, shard := range shards {
+		failed[i] = len(shard) == 0
+		if !failed[i] {
+			present++
+		}
+	}
+	if present < r.DataShards {
+		return failed, tooManyFailures(shards, r.DataShards)
+	}
+	if present == r.DataShards {
+		return failed, nil
+	}
+
+	// consistent returns true if the shards that are not marked in
+	// skip agree with each other. The reconstructions used for this
+	// are not reported to WithReconstructPlan.
+	r.o.reconstructPlan = nil
+	r.o.verifyReconstruct = false
+	test := make([][]byte, r.Shards)
+	consistent := func(skip []bool) (bool, error) {
+		for i := range test {
+			test[i] = nil
+			if !skip[i] {
+				test[i] = shards[i]
+			}
+		}
+		err := r.reconstruct(test, false)
+		if err != nil {
+			return false, err
+		}
+		return r.Verify(test)
+	}
+	ok, err := consistent(failed)
+	if err != nil || ok {
+		return failed, err
+	}
+	if present > r.DataShards+1 {
+		skip := make([]bool, r.Shards)
+		for i := range shards {
+			if failed[i] {
+				continue
+			}
+			copy(skip, failed)
+			skip[i] = true
+			ok, err = consistent(skip)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return skip, nil
+			}
+		}
+	}
+	return failed, ErrCorruptionNotLocated
+}
+
+// MinimalSet returns the indexes of the shards that are sufficient to
+// reconstruct all shards, when the shards marked in available are
+// present. Exactly DataShards indexes are returned, in increasing order.
+//
+// Data shards are preferred, and the set is the one Reconstruct reads,
+// so only these shards need to be fetched, and the rest may be
+// given as nil.
+// The length of available must be equal to Shards, otherwise
+// ErrShardCount is returned. If too few shards are available, a
+// TooManyFailuresError is returned.
+func (r reedSolomon) MinimalSet(available []bool) ([]int, error) {
+	if len(available) != r.Shards {
+		return nil, ErrShardCount
+	}
+	set := make([]int, 0, r.DataShards)
+	var missing []int
+	for i, ok := range available {
+		if !ok {
+			missing = append(missing, i)
+		} else if len(set) < r.DataShards {
+			set = append(set, i)
+		}
+	}
+	if len(set) < r.DataShards {
+		return nil, TooManyFailuresError{Missing: missing, Required: r.DataShards}
+	}
+	return set, nil
+}
+
+// ReconstructLazy recreates the shards marked in failed, and reads
+// the present shards by calling fetch with their index.
+//
+// Only the shards returned by MinimalSet are fetched, one at a time
+// in increasing order, so no more than DataShards shards are read.
+// The returned set contains the fetched and the recreated shards,
+// while the other shards are nil.
+// If fetch returns an error, it is returned together with the index
+// of the shard, and nothing is recreated.
+// The length of failed must be equal to Shards, otherwise
+// ErrShardCount is returned. If nothing has failed, nothing is
+// fetched.
+func (r reedSolomon) ReconstructLazy(failed []bool, fetch func(idx int) ([]byte, error)) ([][]byte, error) {
+	if len(failed) != r.Shards {
+		return nil, ErrShardCount
+	}
+	shards := make([][]byte, r.Shards)
+	available := make([]bool, r.Shards)
+	anyFailed := false
+	for i, f := range failed {
+		available[i] = !f
+		anyFailed = anyFailed || f
+	}
+	if !anyFailed {
+		return shards, nil
+	}
+	set, err := r.MinimalSet(available)
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range set {
+		shards[idx], err = fetch(idx)
+		if err != nil {
+			return nil, fmt.Errorf("fetch shard %d: %w", idx, err)
+		}
+		if len(shards[idx]) == 0 {
+			return nil, fmt.Errorf("fetch shard %d: %w", idx, ErrShardNoData)
+		}
+	}
+	err = r.ReconstructSome(shards, failed)
+	if err != nil {
+		return nil, err
+	}
+	return shards, nil
+}
+
+// ReconstructData will recreate any missing data shards, if possible.
+//
+// Given a list of shards, some of which contain data, fills in the
+// data shards that don't have data.
+//
+// The length of the array must be equal to Shards.
+// You indicate that a shard is missing by setting it to nil.
+//
+// If there are too few shards to reconstruct the missing
+// ones, ErrTooManyFailures will be returned.
+//
+// As the reconstructed shard set may contain missing parity shards,
+// calling the Verify function is likely to fail.
+func (r reedSolomon) ReconstructData(shards [][]byte) error {
+	return r.reconstruct(shards, true)
+}
+
+// DecodeData will store the original data in the data shards,
+// recreating any missing data shards first, if possible.
+//
+// For a systematic encoder, which is the default, this is the same
+// as ReconstructData. When WithNonSystematic is used, the data shards
+// are decoded in place, so afterwards they can be read directly
+// or given to Join, but the set can no longer be verified or
+// reconstructed.
+func (r reedSolomon) DecodeData(shards [][]byte) error {
+	err := r.reconstruct(shards, true)
+	if err != nil || r.unmix == nil {
+		return err
+	}
+	data := shards[:r.DataShards]
+	size := len(data[0])
+	bufs := make([]*[]byte, len(data))
+	out := make([][]byte, len(data))
+	for i := range data {
+		bufs[i] = r.getBuffer(size)
+		out[i] = *bufs[i]
+	}
+	r.codeSomeShards(r.unmix, data, out, r.DataShards, size)
+	for i, buf := range bufs {
+		copy(data[i], out[i])
+		r.o.pool.Put(buf)
+	}
+	return nil
+}
+
+// ErrInvalidEncoder is returned by Transcode if the destination
+// encoder was not created by this package.
+var ErrInvalidEncoder = errors.New("encoder not created by New")
+
+// Transcode returns the shards of the same data encoded with dst,
+// which can have a different number of data and parity shards,
+// and other options.
+//
+// The data shards of the set are concatenated and split into the
+// data shards of dst, without joining the data into a single buffer
+// first, and the parity is then encoded by dst.
+// Missing data shards are reconstructed, without modifying shards.
+// The data is the same as the padded data given to Split, so the
+// original data is recovered by calling Join on dst with the
+// original size.
+//
+// The returned shards are newly allocated, and both sets of shards
+// can be used independently.
+func (r reedSolomon) Transcode(shards [][]byte, dst Encoder) ([][]byte, error) {
+	d, ok := dst.(*reedSolomon)
+	if !ok {
+		return nil, ErrInvalidEncoder
+	}
+	if len(shards) != r.Shards {
+		return nil, ErrShardCount
+	}
+	// Missing shards get no capacity, so nothing is written into them.
+	set := make([][]byte, len(shards))
+	for i, shard := range shards {
+		set[i] = shard[:len(shard):len(shard)]
+	}
+	err := r.reconstruct(set, true)
+	if err != nil {
+		return nil, err
+	}
+	data := set[:r.DataShards]
+	size := len(data[0])
+	if r.unmix != nil {
+		// The data shards are encoded, so decode them to new buffers.
+		data = AllocAligned(r.DataShards, size)
+		r.codeSomeShards(r.unmix, set[:r.DataShards], data, r.DataShards, size)
+	}
+
+	total := size * r.DataShards
+	perShard := (total + d.DataShards - 1) / d.DataShards
+	out := AllocAligned(d.Shards, perShard)
+	i, off := 0, 0
+	for _, in := range data {
+		for len(in) > 0 {
+			n := copy(out[i][off:], in)
+			in = in[n:]
+			off += n
+			if off == perShard {
+				i, off = i+1, 0
+			}
+		}
+	}
+	err = d.Encode(out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReconstructSome will recreate the missing shards where required
+// is true, if possible. Missing shards that are not required are
+// left nil, and only the required outputs are calculated.
+// If a required parity shard is missing, all missing data shards
+// are needed to calculate it, so they will be recreated as well.
+//
+// The length of required must be equal to Shards, otherwise
+// ErrShardCount is returned. Otherwise this functions as Reconstruct.
+func (r reedSolomon) ReconstructSome(shards [][]byte, required []bool) error {
+	if len(required) != r.Shards {
+		return ErrShardCount
+	}
+	var idxs []int
+	for i, req := range required {
+		if req {
+			idxs = append(idxs, i)
+		}
+	}
+	if len(idxs) == 0 {
+		if len(shards) != r.Shards {
+			return ErrShardCount
+		}
+		return checkShards(shards, true)
+	}
+	return r.reconstruct(shards, false, idxs...)
+}
+
+// ReconstructIndexed will place the given shards at their index,
+// and recreate the missing shards, if possible.
+//
+// This can be used when shards are received in arbitrary order,
+// tagged with their index. The map is not modified, and the shards
+// in it are used in the returned set without being copied.
+//
+// If an index is out of range, ErrInvalidShardIndex is returned.
+// Otherwise this functions as Reconstruct.
+func (r reedSolomon) ReconstructIndexed(shards map[int][]byte) ([][]byte, error) {
+	all := make([][]byte, r.Shards)
+	for idx, shard := range shards {
+		if idx < 0 || idx >= r.Shards {
+			return nil, fmt.Errorf("shard index %d: %w", idx, ErrInvalidShardIndex)
+		}
+		all[idx] = shard
+	}
+	err := r.reconstruct(all, false)
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// missingShard returns the buffer to recreate a missing shard in,
+// which is shard if it has the capacity and WithReuseShards is used.
+func (o *options) missingShard(shard []byte, shardSize int) []byte {
+	if o.reuseShards && cap(shard) >= shardSize {
+		return shard[:shardSize]
+	}
+	return AllocAligned(1, shardSize)[0]
 }
 
-func (r reedSolomon) reconstruct(shards [][]byte, idxs ...int) error {
+// reconstructSingle recreates the data shard at index missing, when it
+// is the only missing data shard and the first parity shard is present.
+func (r reedSolomon) reconstructSingle(shards [][]byte, missing, shardSize int) {
+	// Swap the parity shard into the place of the missing shard, which
+	// is where the row takes it as input, and place the output where
+	// the parity was, so no slices must be allocated.
+	parity := shards[r.DataShards]
+	shards[missing], shards[r.DataShards] = parity, r.o.missingShard(shards[missing], shardSize)
+	r.codeSomeShards(r.single[missing:missing+1], shards[:r.DataShards], shards[r.DataShards:], 1, shardSize)
+	shards[missing], shards[r.DataShards] = shards[r.DataShards], parity
+}
+
+// reconstruct will recreate the missing data shards, and unless
+// dataOnly is true, also the missing parity shards.
+// If idxs is specified only shards at those indexes are recreated.
+func (r reedSolomon) reconstruct(shards [][]byte, dataOnly bool, idxs ...int) error {
 	if len(shards) != r.Shards {
-		return ErrTooFewShards
+		return ErrShardCount
 	}
 	// Check that all indexes are in correct range.
 	for _, idx := range idxs {
 		if idx < 0 || idx >= len(shards) {
-			return fmt.Errorf("reconstruct is not allowed. requested index is out of range. %v", idx)
+			return fmt.Errorf("reconstruct index %d: %w", idx, ErrInvalidShardIndex)
 		}
 	}
 
@@ -377,16 +1907,31 @@ func (r reedSolomon) reconstruct(shards [][]byte, idxs ...int) error {
 	// Quick check: are all of the shards present?  If so, there's
 	// nothing to do.
 	numberPresent := 0
+	dataPresent := 0
 	requiredPresent := 0
 	for i := 0; i < r.Shards; i++ {
 		if len(shards[i]) != 0 {
 			if len(idxs) > 0 && contains(idxs, i) {
 				requiredPresent++
 			}
+			if i < r.DataShards {
+				dataPresent++
+			}
 			numberPresent++
 		}
 	}
-	if numberPresent == r.Shards || (len(idxs) > 0 && len(idxs) == requiredPresent) {
+	if numberPresent == r.Shards && r.o.verifyReconstruct {
+		ok, err := r.Verify(shards)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrCorruptionNotLocated
+		}
+		return nil
+	}
+	if numberPresent == r.Shards || (len(idxs) > 0 && len(idxs) == requiredPresent) ||
+		(dataOnly && dataPresent == r.DataShards) {
 		// Cool.  All of the shards data data.  We don't
 		// need to do anything.
 		return nil
@@ -394,7 +1939,19 @@ func (r reedSolomon) reconstruct(shards [][]byte, idxs ...int) error {
 
 	// More complete sanity check
 	if numberPresent < r.DataShards {
-		return ErrTooFewShards
+		return tooManyFailures(shards, r.DataShards)
+	}
+
+	var missing, sources []int
+	var decode matrix
+	if r.o.reconstructPlan != nil {
+		for i, shard := range shards {
+			if len(shard) == 0 {
+				missing = append(missing, i)
+			} else if len(sources) < r.DataShards {
+				sources = append(sources, i)
+			}
+		}
 	}
 
 	// Check if any of requested index is in parity range. In that case we will need to reconstruct all data shards.
@@ -406,78 +1963,132 @@ func (r reedSolomon) reconstruct(shards [][]byte, idxs ...int) error {
 		}
 	}
 
-	// Pull out the rows of the matrix that correspond to the
-	// shards that we have and build a square matrix.  This
-	// matrix could be used to generate the shards that we have
-	// from the original data.
-	//
-	// Also, pull out an array holding just the shards that
-	// correspond to the rows of the submatrix.  These shards
-	// will be the input to the decoding process that re-creates
-	// the missing data shards.
-	subMatrix, _ := newMatrix(r.DataShards, r.DataShards)
-	subShards := make([][]byte, r.DataShards)
-	subMatrixRow := 0
-	for matrixRow := 0; matrixRow < r.Shards && subMatrixRow < r.DataShards; matrixRow++ {
-		if len(shards[matrixRow]) != 0 {
-			for c := 0; c < r.DataShards; c++ {
-				subMatrix[subMatrixRow][c] = r.m[matrixRow][c]
+	// A single missing data shard is recreated from the other data
+	// shards and the first parity shard with a precomputed row, which
+	// is the one the inverted matrix would have, so nothing is inverted.
+	if dataPresent == r.DataShards-1 && len(shards[r.DataShards]) != 0 {
+		idx := 0
+		for len(shards[idx]) != 0 {
+			idx++
+		}
+		if needAllData || len(idxs) == 0 || contains(idxs, idx) {
+			r.reconstructSingle(shards, idx, shardSize)
+			if r.o.reconstructPlan != nil {
+				sources, decode = r.singlePlan(idx)
 			}
-			subShards[subMatrixRow] = shards[matrixRow]
-			subMatrixRow++
+			if dataOnly || numberPresent == r.Shards-1 {
+				if r.o.reconstructPlan != nil {
+					r.reportPlan(shards, missing, sources, decode)
+				}
+				return nil
+			}
+			// Missing parity is recreated below.
+			dataPresent++
 		}
 	}
 
-	// Invert the matrix, so we can go from the encoded shards
-	// back to the original data.  Then pull out the row that
-	// generates the shard that we want to decode.  Note that
-	// since this matrix maps back to the original data, it can
-	// be used to create a data shard, but not a parity shard.
-	dataDecodeMatrix, err := subMatrix.Invert()
-	if err != nil {
-		return err
-	}
-
-	// Re-create any data shards that were missing.
-	//
-	// The input to the coding is all of the shards we actually
-	// have, and the output is the missing data shards.  The computation
-	// is done using the special decode matrix we just built.
 	outputs := make([][]byte, r.ParityShards)
 	matrixRows := make([][]byte, r.ParityShards)
-	outputCount := 0
+	if dataPresent < r.DataShards {
+		// Pull out the rows of the matrix that correspond to the
+		// shards that we have and build a square matrix.  This
+		// matrix could be used to generate the shards that we have
+		// from the original data.
+		//
+		// Also, pull out an array holding just the shards that
+		// correspond to the rows of the submatrix.  These shards
+		// will be the input to the decoding process that re-creates
+		// the missing data shards.
+		subShards := make([][]byte, r.DataShards)
+		validIndices := make([]int, r.DataShards)
+		var used shardBitmap
+		subMatrixRow := 0
+		for matrixRow := 0; matrixRow < r.Shards && subMatrixRow < r.DataShards; matrixRow++ {
+			if len(shards[matrixRow]) != 0 {
+				subShards[subMatrixRow] = shards[matrixRow]
+				validIndices[subMatrixRow] = matrixRow
+				used.set(matrixRow)
+				subMatrixRow++
+			}
+		}
 
-	for iShard := 0; iShard < r.DataShards; iShard++ {
-		if len(shards[iShard]) == 0 {
-			if !needAllData && len(idxs) > 0 && !contains(idxs, iShard) {
-				continue
+		// Invert the matrix, so we can go from the encoded shards
+		// back to the original data.  Then pull out the row that
+		// generates the shard that we want to decode.  Note that
+		// since this matrix maps back to the original data, it can
+		// be used to create a data shard, but not a parity shard.
+		// The same shards are often missing repeatedly, so the inverted
+		// matrix is cached.
+		var dataDecodeMatrix matrix
+		if r.cache != nil {
+			dataDecodeMatrix = r.cache.get(used)
+		}
+		if dataDecodeMatrix == nil {
+			subMatrix, _ := newMatrix(r.DataShards, r.DataShards)
+			for subMatrixRow, validIndex := range validIndices {
+				for c := 0; c < r.DataShards; c++ {
+					subMatrix[subMatrixRow][c] = r.m[validIndex][c]
+				}
 			}
-			shards[iShard] = make([]byte, shardSize)
-			outputs[outputCount] = shards[iShard]
-			matrixRows[outputCount] = dataDecodeMatrix[iShard]
-			outputCount++
+			dataDecodeMatrix, err = subMatrix.invertWith(r.o.field)
+			if err != nil {
+				return err
+			}
+			if r.cache != nil {
+				r.cache.put(used, dataDecodeMatrix)
+			}
+		}
+
+		// Re-create any data shards that were missing.
+		//
+		// The input to the coding is all of the shards we actually
+		// have, and the output is the missing data shards.  The computation
+		// is done using the special decode matrix we just built.
+		outputCount := 0
+		for iShard := 0; iShard < r.DataShards; iShard++ {
+			if len(shards[iShard]) == 0 {
+				if !needAllData && len(idxs) > 0 && !contains(idxs, iShard) {
+					continue
+				}
+				shards[iShard] = r.o.missingShard(shards[iShard], shardSize)
+				outputs[outputCount] = shards[iShard]
+				matrixRows[outputCount] = dataDecodeMatrix[iShard]
+				outputCount++
+			}
+		}
+		r.codeSomeShards(matrixRows, subShards, outputs[:outputCount], outputCount, shardSize)
+		decode = dataDecodeMatrix
+	}
+
+	if dataOnly {
+		if r.o.reconstructPlan != nil {
+			r.reportPlan(shards, missing, sources, decode)
 		}
+		return nil
 	}
-	r.codeSomeShards(matrixRows, subShards, outputs[:outputCount], outputCount, shardSize)
+
 	// Now that we have all of the data shards intact, we can
 	// compute any of the parity that is missing.
 	//
 	// The input to the coding is ALL of the data shards, including
 	// any that we just calculated.  The output is whichever of the
 	// data shards were missing.
-	outputCount = 0
+	outputCount := 0
 	for iShard := r.DataShards; iShard < r.Shards; iShard++ {
 		if len(shards[iShard]) == 0 {
 			if len(idxs) > 0 && !contains(idxs, iShard) {
 				continue
 			}
-			shards[iShard] = make([]byte, shardSize)
+			shards[iShard] = r.o.missingShard(shards[iShard], shardSize)
 			outputs[outputCount] = shards[iShard]
 			matrixRows[outputCount] = r.parity[iShard-r.DataShards]
 			outputCount++
 		}
 	}
 	r.codeSomeShards(matrixRows, shards[:r.DataShards], outputs[:outputCount], outputCount, shardSize)
+	if r.o.reconstructPlan != nil {
+		r.reportPlan(shards, missing, sources, decode)
+	}
 	return nil
 }
 
@@ -494,6 +2105,10 @@ func contains(src []int, i int) bool {
 // to fill the number of shards.
 var ErrShortData = errors.New("not enough data to fill the number of requested shards")
 
+// ErrInvalidSize is returned by Join and its variants if the requested
+// output size is negative.
+var ErrInvalidSize = errors.New("output size must not be negative")
+
 // Split a data slice into the number of shards given to the encoder,
 // and create empty parity shards.
 //
@@ -504,21 +2119,42 @@ var ErrShortData = errors.New("not enough data to fill the number of requested s
 // There must be at least 1 byte otherwise ErrShortData will be
 // returned.
 //
+// The shards are returned in canonical order: the data shards in
+// the order they appear in data, followed by the parity shards.
+// All other functions expect shards in this order, so the index of
+// each shard must be stored with it.
+//
 // The data will not be copied, except for the last shard, so you
 // should not modify the data of the input slice afterwards.
 func (r reedSolomon) Split(data []byte) ([][]byte, error) {
+	return r.split(data, r.Shards)
+}
+
+// SplitData functions as Split, but only returns the data shards,
+// and does not allocate space for parity.
+//
+// The parity can then be allocated when needed, and encoded with
+// EncodeInto, or by appending ParityShards nil shards to the data
+// shards and calling Encode, which allocates them.
+func (r reedSolomon) SplitData(data []byte) ([][]byte, error) {
+	return r.split(data, r.DataShards)
+}
+
+// split data into the data shards, and return n shards, where
+// the shards following the data shards are zero.
+func (r reedSolomon) split(data []byte, n int) ([][]byte, error) {
 	if len(data) == 0 {
 		return nil, ErrShortData
 	}
 	// Calculate number of bytes per shard.
 	perShard := (len(data) + r.DataShards - 1) / r.DataShards
 
-	// Pad data to r.Shards*perShard.
-	padding := make([]byte, (r.Shards*perShard)-len(data))
+	// Pad data to n*perShard.
+	padding := make([]byte, (n*perShard)-len(data))
 	data = append(data, padding...)
 
 	// Split into equal-length shards.
-	dst := make([][]byte, r.Shards)
+	dst := make([][]byte, n)
 	for i := range dst {
 		dst[i] = data[:perShard]
 		data = data[perShard:]
@@ -529,14 +2165,20 @@ func (r reedSolomon) Split(data []byte) ([][]byte, error) {
 
 // Join the shards and write the data segment to dst.
 //
-// Only the data shards are considered.
+// Only the data shards are considered, and they must be in the
+// canonical order returned by Split. Use JoinReordered if they are not.
 // You must supply the exact output size you want.
-// If there are to few shards given, ErrTooFewShards will be returned.
+// If there are to few shards given, ErrShardCount will be returned.
 // If the total data size is less than outSize, ErrShortData will be returned.
+// A negative outSize returns ErrInvalidSize, while an outSize of 0 writes
+// nothing, so empty objects can be joined from shards of any size.
 func (r reedSolomon) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	if outSize < 0 {
+		return ErrInvalidSize
+	}
 	// Do we have enough shards?
 	if len(shards) < r.DataShards {
-		return ErrTooFewShards
+		return ErrShardCount
 	}
 	shards = shards[:r.DataShards]
 
@@ -564,3 +2206,140 @@ func (r reedSolomon) Join(dst io.Writer, shards [][]byte, outSize int) error {
 	}
 	return nil
 }
+
+// ErrHashMismatch is returned by JoinVerified if the joined data
+// does not match the hash.
+var ErrHashMismatch = errors.New("joined data does not match hash")
+
+// JoinVerified functions as Join, but also hashes the data as it is
+// written to dst, and returns ErrHashMismatch if the result of h is not
+// wholeObjectHash.
+//
+// h is reset before use, and can be any hash, for instance one from
+// crypto/sha256, as long as the same was used for wholeObjectHash.
+// Since the check can only be done when all data is written,
+// dst has received the data when ErrHashMismatch is returned, so it
+// must be discarded by the caller.
+func (r reedSolomon) JoinVerified(dst io.Writer, shards [][]byte, outSize int, wholeObjectHash []byte, h hash.Hash) error {
+	h.Reset()
+	err := r.Join(io.MultiWriter(dst, h), shards, outSize)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Sum(nil), wholeObjectHash) {
+		return ErrHashMismatch
+	}
+	return nil
+}
+
+// Matrix returns a copy of the encoding matrix rows used to
+// generate the parity shards.
+// Row i contains the coefficients that are multiplied with
+// each data shard and added to produce parity shard i.
+func (r reedSolomon) Matrix() [][]byte {
+	rows := make([][]byte, len(r.parity))
+	for i := range rows {
+		rows[i] = append([]byte(nil), r.parity[i]...)
+	}
+	return rows
+}
+
+// SplitPadded functions as Split, but also returns the number of
+// zero bytes that were added after the data in the data shards.
+//
+// The padding can be stored together with the shards and given
+// to JoinTrim, so the original data can be recovered without
+// knowing its size.
+func (r reedSolomon) SplitPadded(data []byte) ([][]byte, int, error) {
+	shards, err := r.Split(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return shards, len(shards[0])*r.DataShards - len(data), nil
+}
+
+// ErrInvalidOrder is returned by JoinReordered if the order
+// is not a permutation of the shard indexes.
+var ErrInvalidOrder = errors.New("shard order is not a permutation")
+
+// JoinReordered functions as Join, but takes shards in arbitrary
+// order. order[i] must be the canonical index of shards[i], as
+// returned by Split, so order must be a permutation of the numbers
+// 0 to len(shards)-1 and have the same length as shards, otherwise
+// ErrInvalidOrder is returned.
+// Neither shards nor order are modified.
+func (r reedSolomon) JoinReordered(dst io.Writer, shards [][]byte, order []int, outSize int) error {
+	if len(order) != len(shards) {
+		return ErrInvalidOrder
+	}
+	if len(shards) < r.DataShards || len(shards) > r.Shards {
+		return ErrShardCount
+	}
+	sorted := make([][]byte, len(shards))
+	seen := make([]bool, len(shards))
+	for i, idx := range order {
+		if idx < 0 || idx >= len(shards) || seen[idx] {
+			return ErrInvalidOrder
+		}
+		seen[idx] = true
+		sorted[idx] = shards[i]
+	}
+	return r.Join(dst, sorted, outSize)
+}
+
+// ErrInvalidPadding is returned by JoinTrim if the padding is negative
+// or would leave no data.
+var ErrInvalidPadding = errors.New("invalid padding size")
+
+// JoinTrim joins the data shards and writes them to dst, leaving
+// out the padding returned by SplitPadded.
+//
+// Only the data shards are considered, and they must all be
+// present and of the same size.
+// If there are to few shards given, ErrShardCount will be returned.
+func (r reedSolomon) JoinTrim(dst io.Writer, shards [][]byte, padding int) error {
+	if len(shards) < r.DataShards {
+		return ErrShardCount
+	}
+	err := checkShards(shards[:r.DataShards], false)
+	if err != nil {
+		return err
+	}
+	size := len(shards[0])
+	if padding < 0 || padding >= size*r.DataShards {
+		return ErrInvalidPadding
+	}
+	return r.Join(dst, shards, size*r.DataShards-padding)
+}
+
+// Backend returns the name of the Galois field multiplication
+// implementation used by the encoder.
+// This is "avx2", "ssse3", "neon" or "pure-go", depending
+// on the detected CPU features and the options given to New.
+//
+// The backend only affects speed. Encoding the same data with the
+// same matrix gives identical parity on all platforms.
+func (r reedSolomon) Backend() string {
+	return r.o.backend()
+}
+
+// StorageOverhead returns the ratio of parity to data,
+// ParityShards/DataShards, so 0.5 means that 50% is added to the
+// size of the data.
+func (r reedSolomon) StorageOverhead() float64 {
+	return float64(r.ParityShards) / float64(r.DataShards)
+}
+
+// RepairDegree returns the number of shards read to recreate a single
+// missing shard, which is DataShards, as every shard is recreated
+// from DataShards other shards.
+func (r reedSolomon) RepairDegree() int {
+	return r.DataShards
+}
+
+// FaultTolerance returns the number of shards that can be lost while
+// the data can still be recreated, which is ParityShards, as any
+// DataShards shards are sufficient.
+func (r reedSolomon) FaultTolerance() int {
+	return r.ParityShards
+}
@@ -0,0 +1,341 @@
+/**
+ * Reed-Solomon Coding over 16-bit values.
+ */
+
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// Encoder16 is an interface to encode Reed-Solomon parity sets over
+// GF(2^16), which allows up to 65536 data+parity shards.
+//
+// Each shard is read as a sequence of 16 bit symbols stored little
+// endian, so byte 2*n is the low and byte 2*n+1 is the high part of
+// symbol n. Shard sizes must therefore be a multiple of 2.
+//
+// The output is not compatible with the GF(2^8) Encoder, and all
+// calculations are done in pure Go, so it is considerably slower.
+// Only use this if you need more than 256 shards.
+type Encoder16 interface {
+	// Encodes parity for a set of data shards.
+	// Input is 'shards' containing data shards followed by parity shards.
+	// The number of shards must match the number given to New16().
+	// Each shard is a byte array, and they must all be the same size,
+	// which must be a multiple of 2.
+	// The parity shards will always be overwritten and the data shards
+	// will remain the same.
+	Encode(shards [][]byte) error
+
+	// Verify returns true if the parity shards contain correct data.
+	// The data is the same format as Encode. No data is modified.
+	Verify(shards [][]byte) (bool, error)
+
+	// Reconstruct will recreate the missing shards if possible.
+	//
+	// Given a list of shards, some of which contain data, fills in the
+	// ones that don't have data.
+	//
+	// The length of the array must be equal to the total number of shards.
+	// You indicate that a shard is missing by setting it to nil.
+	//
+	// If there are too few shards to reconstruct the missing
+	// ones, ErrTooManyFailures will be returned.
+	Reconstruct(shards [][]byte) error
+
+	// Split a data slice into the number of shards given to the encoder,
+	// and create empty parity shards.
+	//
+	// The data will be split into equally sized shards, with a size
+	// that is a multiple of 2. The last shard will contain extra zeros
+	// if needed.
+	Split(data []byte) ([][]byte, error)
+
+	// Join the shards and write the data segment to dst.
+	//
+	// Only the data shards are considered.
+	// You must supply the exact output size you want.
+	Join(dst io.Writer, shards [][]byte, outSize int) error
+}
+
+// reedSolomon16 contains the parity rows of the matrix
+// for a specific distribution of datashards and parity shards.
+// Construct if using New16()
+type reedSolomon16 struct {
+	DataShards   int // Number of data shards, should not be modified.
+	ParityShards int // Number of parity shards, should not be modified.
+	Shards       int // Total number of shards. Calculated, and should not be modified.
+	parity       matrix16
+}
+
+// ErrMaxShardNum16 will be returned by New16, if you attempt to create
+// an Encoder where data and parity shards are bigger than the order
+// of GF(2^16).
+var ErrMaxShardNum16 = errors.New("cannot create Encoder with more than 65536 data+parity shards")
+
+// ErrOddShardSize is returned by the GF(2^16) encoder if the shard
+// size is not a multiple of 2.
+var ErrOddShardSize = errors.New("shard size must be a multiple of 2")
+
+// New16 creates a new GF(2^16) encoder and initializes it to
+// the number of data shards and parity shards that
+// you want to use. You can reuse this encoder.
+// Note that the maximum number of total shards is 65536.
+//
+// Reconstruction needs to invert a matrix with dataShards rows, so
+// very high numbers of data shards will be slow to reconstruct.
+func New16(dataShards, parityShards int) (Encoder16, error) {
+	if dataShards <= 0 || parityShards < 0 {
+		return nil, ErrInvShardNum
+	}
+	if dataShards > fieldSize16 || parityShards > fieldSize16 || dataShards+parityShards > fieldSize16 {
+		return nil, ErrMaxShardNum16
+	}
+	initGF16()
+
+	r := reedSolomon16{
+		DataShards:   dataShards,
+		ParityShards: parityShards,
+		Shards:       dataShards + parityShards,
+	}
+	if parityShards > 0 {
+		var err error
+		r.parity, err = buildParityCauchy16(dataShards, parityShards)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &r, nil
+}
+
+// checkShards16 functions as checkShards, and also checks
+// that the shard size is a multiple of 2.
+func checkShards16(shards [][]byte, nilok bool) error {
+	err := checkShards(shards, nilok)
+	if err != nil {
+		return err
+	}
+	if shardSize(shards)&1 != 0 {
+		return ErrOddShardSize
+	}
+	return nil
+}
+
+// Encodes parity for a set of data shards.
+// An array 'shards' containing data shards followed by parity shards.
+// The number of shards must match the number given to New16.
+// Each shard is a byte array, and they must all be the same size.
+// The parity shards will always be overwritten and the data shards
+// will remain the same.
+func (r reedSolomon16) Encode(shards [][]byte) error {
+	if len(shards) != r.Shards {
+		return ErrShardCount
+	}
+	err := checkShards16(shards, false)
+	if err != nil {
+		return err
+	}
+	r.codeSomeShards(r.parity, shards[:r.DataShards], shards[r.DataShards:], len(shards[0]))
+	return nil
+}
+
+// Verify returns true if the parity shards contain the right data.
+// The data is the same format as Encode. No data is modified.
+func (r reedSolomon16) Verify(shards [][]byte) (bool, error) {
+	if len(shards) != r.Shards {
+		return false, ErrShardCount
+	}
+	err := checkShards16(shards, false)
+	if err != nil {
+		return false, err
+	}
+	outputs := createSlice(r.ParityShards, len(shards[0]))
+	r.codeSomeShards(r.parity, shards[:r.DataShards], outputs, len(shards[0]))
+	for i, calc := range outputs {
+		if !bytes.Equal(calc, shards[r.DataShards+i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// codeSomeShards multiplies the matrix rows with the inputs, and
+// stores the result in outputs. The work is split into several
+// goroutines if the shards are big enough.
+func (r reedSolomon16) codeSomeShards(matrixRows matrix16, inputs, outputs [][]byte, byteCount int) {
+	if len(outputs) == 0 {
+		return
+	}
+	do := byteCount
+	if runtime.GOMAXPROCS(0) > 1 && byteCount > minSplitSize {
+		do = byteCount / maxGoroutines
+		if do < minSplitSize {
+			do = minSplitSize
+		}
+		// Never split a symbol.
+		do = (do + 1) &^ 1
+	}
+	var wg sync.WaitGroup
+	for start := 0; start < byteCount; start += do {
+		stop := start + do
+		if stop > byteCount {
+			stop = byteCount
+		}
+		wg.Add(1)
+		go func(start, stop int) {
+			defer wg.Done()
+			for c, in := range inputs {
+				in = in[start:stop]
+				for iRow, out := range outputs {
+					if c == 0 {
+						gal16MulSlice(matrixRows[iRow][c], in, out[start:stop])
+					} else {
+						gal16MulSliceXor(matrixRows[iRow][c], in, out[start:stop])
+					}
+				}
+			}
+		}(start, stop)
+	}
+	wg.Wait()
+}
+
+// Reconstruct will recreate the missing shards, if possible.
+//
+// Given a list of shards, some of which contain data, fills in the
+// ones that don't have data.
+//
+// The length of the array must be equal to Shards.
+// You indicate that a shard is missing by setting it to nil.
+//
+// If there are too few shards to reconstruct the missing
+// ones, ErrTooManyFailures will be returned.
+//
+// The reconstructed shard set is complete, but integrity is not verified.
+// Use the Verify function to check if data set is ok.
+func (r reedSolomon16) Reconstruct(shards [][]byte) error {
+	if len(shards) != r.Shards {
+		return ErrShardCount
+	}
+	err := checkShards16(shards, true)
+	if err != nil {
+		return err
+	}
+	shardSize := shardSize(shards)
+
+	// Quick check: are all of the shards present?  If so, there's
+	// nothing to do.
+	numberPresent := 0
+	for i := range shards {
+		if len(shards[i]) != 0 {
+			numberPresent++
+		}
+	}
+	if numberPresent == r.Shards {
+		return nil
+	}
+	if numberPresent < r.DataShards {
+		return tooManyFailures(shards, r.DataShards)
+	}
+
+	// Pull out the rows of the matrix that correspond to the
+	// shards that we have and build a square matrix.
+	// Data shards correspond to rows of the identity matrix.
+	subMatrix, _ := newMatrix16(r.DataShards, r.DataShards)
+	subShards := make([][]byte, r.DataShards)
+	subMatrixRow := 0
+	for matrixRow := 0; matrixRow < r.Shards && subMatrixRow < r.DataShards; matrixRow++ {
+		if len(shards[matrixRow]) == 0 {
+			continue
+		}
+		if matrixRow < r.DataShards {
+			subMatrix[subMatrixRow][matrixRow] = 1
+		} else {
+			copy(subMatrix[subMatrixRow], r.parity[matrixRow-r.DataShards])
+		}
+		subShards[subMatrixRow] = shards[matrixRow]
+		subMatrixRow++
+	}
+
+	// Invert the matrix, so we can go from the encoded shards
+	// back to the original data.
+	dataDecodeMatrix, err := subMatrix.Invert()
+	if err != nil {
+		return err
+	}
+
+	// Re-create any data shards that were missing.
+	var outputs [][]byte
+	var matrixRows matrix16
+	for iShard := 0; iShard < r.DataShards; iShard++ {
+		if len(shards[iShard]) == 0 {
+			shards[iShard] = make([]byte, shardSize)
+			outputs = append(outputs, shards[iShard])
+			matrixRows = append(matrixRows, dataDecodeMatrix[iShard])
+		}
+	}
+	r.codeSomeShards(matrixRows, subShards, outputs, shardSize)
+
+	// Now that we have all of the data shards intact, we can
+	// compute any of the parity that is missing.
+	outputs, matrixRows = outputs[:0], matrixRows[:0]
+	for iShard := r.DataShards; iShard < r.Shards; iShard++ {
+		if len(shards[iShard]) == 0 {
+			shards[iShard] = make([]byte, shardSize)
+			outputs = append(outputs, shards[iShard])
+			matrixRows = append(matrixRows, r.parity[iShard-r.DataShards])
+		}
+	}
+	r.codeSomeShards(matrixRows, shards[:r.DataShards], outputs, shardSize)
+	return nil
+}
+
+// Split a data slice into the number of shards given to the encoder,
+// and create empty parity shards.
+//
+// The data will be split into equally sized shards, with a size
+// that is a multiple of 2.
+// If the data size isn't divisible by twice the number of shards,
+// the last shard will contain extra zeros.
+//
+// There must be at least 1 byte otherwise ErrShortData will be
+// returned.
+//
+// The data will not be copied, except for the last shard, so you
+// should not modify the data of the input slice afterwards.
+func (r reedSolomon16) Split(data []byte) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrShortData
+	}
+	// Calculate number of bytes per shard, rounded up to whole symbols.
+	perShard := (len(data) + r.DataShards - 1) / r.DataShards
+	perShard = (perShard + 1) &^ 1
+
+	// Pad data to r.Shards*perShard.
+	padding := make([]byte, (r.Shards*perShard)-len(data))
+	data = append(data, padding...)
+
+	// Split into equal-length shards.
+	dst := make([][]byte, r.Shards)
+	for i := range dst {
+		dst[i] = data[:perShard]
+		data = data[perShard:]
+	}
+
+	return dst, nil
+}
+
+// Join the shards and write the data segment to dst.
+//
+// Only the data shards are considered.
+// You must supply the exact output size you want.
+// If there are to few shards given, ErrShardCount will be returned.
+// If the total data size is less than outSize, ErrShortData will be returned.
+func (r reedSolomon16) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	// The data shards have the same layout as GF(2^8)
+	return reedSolomon{DataShards: r.DataShards}.Join(dst, shards, outSize)
+}
@@ -0,0 +1,201 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestGalois16(t *testing.T) {
+	initGF16()
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 100000; i++ {
+		a := uint16(rng.Intn(fieldSize16))
+		b := uint16(rng.Intn(fieldSize16))
+		c := uint16(rng.Intn(fieldSize16))
+		if x, y := gal16Multiply(a, gal16Multiply(b, c)), gal16Multiply(gal16Multiply(a, b), c); x != y {
+			t.Fatal("multiply is not associative:", x, "!=", y)
+		}
+		if x, y := gal16Multiply(a, b^c), gal16Multiply(a, b)^gal16Multiply(a, c); x != y {
+			t.Fatal("multiply is not distributive:", x, "!=", y)
+		}
+		if b != 0 && gal16Multiply(gal16Divide(a, b), b) != a {
+			t.Fatal("divide is not inverse of multiply", a, b)
+		}
+	}
+	for a := 0; a < fieldSize16; a += 251 {
+		power := uint16(1)
+		for n := 0; n < 1000; n++ {
+			if x := gal16Exp(uint16(a), n); x != power {
+				t.Fatal(x, "!=", power)
+			}
+			power = gal16Multiply(power, uint16(a))
+		}
+	}
+
+	in := make([]byte, 1000)
+	fillRandom(in)
+	out := make([]byte, len(in))
+	gal16MulSlice(12345, in, out)
+	for i := 0; i < len(in); i += 2 {
+		v := uint16(in[i]) | uint16(in[i+1])<<8
+		want := gal16Multiply(12345, v)
+		if got := uint16(out[i]) | uint16(out[i+1])<<8; got != want {
+			t.Fatal("gal16MulSlice mismatch at", i, got, "!=", want)
+		}
+	}
+	gal16MulSliceXor(12345, in, out)
+	for i, v := range out {
+		if v != 0 {
+			t.Fatal("gal16MulSliceXor mismatch at", i)
+		}
+	}
+}
+
+func TestEncoding16(t *testing.T) {
+	perShard := 2000
+	r, err := New16(300, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := make([][]byte, 330)
+	for s := range shards {
+		shards[s] = make([]byte, perShard)
+	}
+
+	rand.Seed(0)
+	for s := 0; s < 300; s++ {
+		fillRandom(shards[s])
+	}
+
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("Verification failed")
+	}
+
+	// Remove 30 shards, both data and parity.
+	want := make([][]byte, len(shards))
+	copy(want, shards)
+	for i := 0; i < 30; i++ {
+		shards[i*11] = nil
+	}
+	err = r.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range shards {
+		if !bytes.Equal(shards[i], want[i]) {
+			t.Fatal("reconstructed shard mismatch", i)
+		}
+	}
+
+	shards[0][0]++
+	ok, err = r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("Verification did not fail")
+	}
+
+	for i := 0; i < 31; i++ {
+		shards[i] = nil
+	}
+	err = r.Reconstruct(shards)
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+
+	err = r.Encode(make([][]byte, 1))
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+	odd := createSlice(330, 11)
+	err = r.Encode(odd)
+	if err != ErrOddShardSize {
+		t.Errorf("expected %v, got %v", ErrOddShardSize, err)
+	}
+}
+
+func TestSplitJoin16(t *testing.T) {
+	r, err := New16(5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{1, 9, 10, 11, 250001} {
+		data := make([]byte, size)
+		fillRandom(data)
+		shards, err := r.Split(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(shards[0])&1 != 0 {
+			t.Fatal("odd shard size", len(shards[0]))
+		}
+		err = r.Encode(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards[1], shards[6] = nil, nil
+		err = r.Reconstruct(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := new(bytes.Buffer)
+		err = r.Join(buf, shards, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Fatal("recovered data does not match original, size", size)
+		}
+	}
+}
+
+func TestNew16(t *testing.T) {
+	tests := []struct {
+		data, parity int
+		err          error
+	}{
+		{1000, 100, nil},
+		{1, 0, nil},
+		{65535, 1, nil},
+		{65536, 1, ErrMaxShardNum16},
+		{0, 1, ErrInvShardNum},
+		{1, -1, ErrInvShardNum},
+		{256, int(^uint(0) >> 1), ErrMaxShardNum16},
+	}
+	for _, test := range tests {
+		_, err := New16(test.data, test.parity)
+		if err != test.err {
+			t.Errorf("New16(%v, %v): expected %v, got %v", test.data, test.parity, test.err, err)
+		}
+	}
+}
+
+func BenchmarkEncode16x200x20x10000(b *testing.B) {
+	r, err := New16(200, 20)
+	if err != nil {
+		b.Fatal(err)
+	}
+	shards := createSlice(220, 10000)
+	for s := 0; s < 200; s++ {
+		fillRandom(shards[s])
+	}
+	b.SetBytes(200 * 10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err = r.Encode(shards)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
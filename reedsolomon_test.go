@@ -9,8 +9,14 @@ package reedsolomon
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
 	"math/rand"
 	"runtime"
+	"sync"
 	"testing"
 )
 
@@ -43,21 +49,1882 @@ func TestEncoding(t *testing.T) {
 	}
 
 	err = r.Encode(make([][]byte, 1))
-	if err != ErrTooFewShards {
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
 
 	badShards := make([][]byte, 13)
 	badShards[0] = make([]byte, 1)
 	err = r.Encode(badShards)
+	if err != ErrShardNoData {
+		t.Errorf("expected %v, got %v", ErrShardNoData, err)
+	}
+	badShards = append([][]byte{}, shards...)
+	badShards[3] = badShards[3][:perShard-1]
+	err = r.Encode(badShards)
+	if err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+
+	// A nil data shard must not be treated as zeros.
+	badShards = append([][]byte{}, shards...)
+	badShards[5] = nil
+	err = r.Encode(badShards)
+	if err != ErrShardNoData {
+		t.Errorf("expected %v, got %v", ErrShardNoData, err)
+	}
+
+	// Nil parity shards are allocated.
+	withParity := append([][]byte{}, shards...)
+	withParity[10], withParity[12] = nil, nil
+	err = r.Encode(withParity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 10; i < 13; i++ {
+		if !bytes.Equal(withParity[i], shards[i]) {
+			t.Errorf("parity shard %d mismatch", i)
+		}
+	}
+}
+
+func TestEncodeBatch(t *testing.T) {
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rand.Seed(0)
+	objects := make([][][]byte, 37)
+	for i := range objects {
+		// Mix small and big sets.
+		perShard := 100 + i*31
+		if i%5 == 0 {
+			perShard = 20000
+		}
+		objects[i] = make([][]byte, 13)
+		for s := range objects[i] {
+			objects[i][s] = make([]byte, perShard)
+		}
+		for s := 0; s < 10; s++ {
+			fillRandom(objects[i][s])
+		}
+	}
+	// Parity is allocated as by Encode.
+	objects[7][10], objects[7][12] = nil, nil
+	err = r.EncodeBatch(objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, shards := range objects {
+		ok, err := r.Verify(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("verification failed on set", i)
+		}
+	}
+
+	err = r.EncodeBatch(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing must be written if a set is invalid.
+	for s := 10; s < 13; s++ {
+		objects[0][s][0] ^= 1
+	}
+	objects[3] = objects[3][:12]
+	err = r.EncodeBatch(objects)
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+	ok, err := r.Verify(objects[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("parity was written for a batch with an invalid set")
+	}
+	objects[3] = objects[4]
+	objects[5][2] = objects[5][2][:10]
+	err = r.EncodeBatch(objects)
+	if err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+}
+
+func TestEncodeSingle(t *testing.T) {
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 100000)
+	fillRandom(data)
+	shards, err := r.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := make([]byte, len(shards[0]))
+	for p := 0; p < 3; p++ {
+		err = r.EncodeSingle(shards[:10], p, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(dst, shards[10+p]) {
+			t.Fatal("parity mismatch for index", p)
+		}
+	}
+
+	for _, idx := range []int{-1, 3, 10} {
+		err = r.EncodeSingle(shards[:10], idx, dst)
+		if err != ErrInvalidShardIndex {
+			t.Errorf("index %d: expected %v, got %v", idx, ErrInvalidShardIndex, err)
+		}
+	}
+	err = r.EncodeSingle(shards[:9], 0, dst)
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+	err = r.EncodeSingle(shards[:10], 0, dst[:10])
+	if err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+}
+
+func TestEncodeInto(t *testing.T) {
+	perShard := 50000
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := make([][]byte, 13)
+	for s := range shards {
+		shards[s] = make([]byte, perShard)
+	}
+	rand.Seed(0)
+	for s := 0; s < 10; s++ {
+		fillRandom(shards[s])
+	}
+	parity := make([][]byte, 3)
+	for s := range parity {
+		parity[s] = make([]byte, perShard)
+	}
+	err = r.EncodeInto(shards[:10], parity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range parity {
+		if !bytes.Equal(parity[i], shards[10+i]) {
+			t.Fatal("parity mismatch on shard", 10+i)
+		}
+	}
+
+	err = r.EncodeInto(shards[:9], parity)
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+	err = r.EncodeInto(shards[:10], parity[:2])
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+	parity[1] = parity[1][:100]
+	err = r.EncodeInto(shards[:10], parity)
+	if err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+	parity[1] = nil
+	err = r.EncodeInto(shards[:10], parity)
+	if err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+}
+
+func TestBufferPool(t *testing.T) {
+	// Fill the pool with small and dirty buffers,
+	// which must not change the results.
+	pool := &sync.Pool{}
+	for i := 0; i < 10; i++ {
+		buf := make([]byte, 100+i*10000)
+		fillRandom(buf)
+		pool.Put(&buf)
+	}
+	r, err := New(10, 3, WithBufferPool(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := newExt(10, 3, WithBufferPool(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 100000)
+	fillRandom(data)
+	shards, err := r.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		for _, enc := range []Encoder{r, r2} {
+			ok, err := enc.Verify(shards)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Fatal("verification failed")
+			}
+		}
+	}
+
+	newData := make([]byte, len(shards[0]))
+	fillRandom(newData)
+	err = r2.Update(shards, 3, shards[3], newData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards[3] = newData
+	ok, err := r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("verification failed after update")
+	}
+	shards[3][0] ^= 1
+	ok, err = r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("verification did not fail")
+	}
+}
+
+func TestParityDelta(t *testing.T) {
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := r.AllocAligned(5000)
+	for s := 0; s < 10; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldData := make([][]byte, 10)
+	newData := make([][]byte, 10)
+	for _, idx := range []int{1, 5, 9} {
+		oldData[idx] = shards[idx]
+		newData[idx] = make([]byte, 5000)
+		fillRandom(newData[idx])
+	}
+	deltas, err := r.ParityDelta(oldData, newData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deltas) != 3 {
+		t.Fatalf("expected 3 deltas, got %d", len(deltas))
+	}
+	for i, delta := range deltas {
+		parity := shards[10+i]
+		for j := range parity {
+			parity[j] ^= delta[j]
+		}
+	}
+	for _, idx := range []int{1, 5, 9} {
+		shards[idx] = newData[idx]
+	}
+	ok, err := r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("verification failed after applying deltas")
+	}
+
+	newData[1] = newData[1][:10]
+	_, err = r.ParityDelta(oldData, newData)
+	if err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+	_, err = r.ParityDelta(make([][]byte, 10), make([][]byte, 10))
+	if err != ErrShardNoData {
+		t.Errorf("expected %v, got %v", ErrShardNoData, err)
+	}
+	_, err = r.ParityDelta(oldData[:9], newData)
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+}
+
+func TestEncodePartial(t *testing.T) {
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := r.AllocAligned(5000)
+	for s := 0; s < 10; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Incremental encoding from zero parity.
+	parity := AllocAligned(3, 5000)
+	for _, changed := range [][]int{{0, 1, 2}, {}, {3}, {9, 4, 8, 5, 6, 7}} {
+		err = r.EncodePartial(shards[:10], changed, parity)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range parity {
+		if !bytes.Equal(parity[i], shards[10+i]) {
+			t.Fatalf("parity %d does not match Encode", i)
+		}
+	}
+
+	// Update by delta, reading only the changed shards.
+	delta := make([][]byte, 10)
+	for _, idx := range []int{2, 7} {
+		newData := make([]byte, 5000)
+		fillRandom(newData)
+		delta[idx] = make([]byte, 5000)
+		for j := range newData {
+			delta[idx][j] = shards[idx][j] ^ newData[j]
+		}
+		shards[idx] = newData
+	}
+	err = r.EncodePartial(delta, []int{7, 2}, shards[10:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("verification failed after partial update")
+	}
+
+	err = r.EncodePartial(delta, []int{2, 2}, shards[10:])
+	if err != ErrInvalidShardIndex {
+		t.Errorf("expected %v, got %v", ErrInvalidShardIndex, err)
+	}
+	err = r.EncodePartial(delta, []int{10}, shards[10:])
+	if err != ErrInvalidShardIndex {
+		t.Errorf("expected %v, got %v", ErrInvalidShardIndex, err)
+	}
+	err = r.EncodePartial(delta, []int{1}, shards[10:])
+	if err != ErrShardNoData {
+		t.Errorf("expected %v, got %v", ErrShardNoData, err)
+	}
+	err = r.EncodePartial(delta, []int{2, 7}, shards[10:12])
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+	delta[7] = delta[7][:100]
+	err = r.EncodePartial(delta, []int{2, 7}, shards[10:])
 	if err != ErrShardSize {
 		t.Errorf("expected %v, got %v", ErrShardSize, err)
 	}
 }
 
-func TestReconstruct(t *testing.T) {
-	perShard := 50000
-	r, err := New(10, 3)
+func TestUpdate(t *testing.T) {
+	perShard := 50000
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := make([][]byte, 13)
+	for s := range shards {
+		shards[s] = make([]byte, perShard)
+	}
+	rand.Seed(0)
+	for s := 0; s < 10; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, idx := range []int{0, 4, 9} {
+		newData := make([]byte, perShard)
+		fillRandom(newData)
+		err = r.Update(shards, idx, shards[idx], newData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards[idx] = newData
+		ok, err := r.Verify(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("Verification failed after updating shard", idx)
+		}
+	}
+
+	// Data shards are not needed.
+	parityOnly := make([][]byte, 13)
+	copy(parityOnly[10:], shards[10:])
+	newData := make([]byte, perShard)
+	err = r.Update(parityOnly, 2, shards[2], newData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards[2] = newData
+	ok, err := r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("Verification failed")
+	}
+
+	err = r.Update(shards, 10, shards[0], shards[0])
+	if err != ErrInvalidShardIndex {
+		t.Errorf("expected %v, got %v", ErrInvalidShardIndex, err)
+	}
+	err = r.Update(shards, 0, shards[0], shards[0][:10])
+	if err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+	err = r.Update(shards[:12], 0, shards[0], shards[0])
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+}
+
+func TestPureGo(t *testing.T) {
+	perShard := 50000
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rGo, err := newExt(10, 3, WithPureGo(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rGo.Backend() != "pure-go" {
+		t.Fatal("WithPureGo did not disable assembly, got backend", rGo.Backend())
+	}
+	t.Log("Default backend:", r.Backend())
+	shards := make([][]byte, 13)
+	for s := range shards {
+		shards[s] = make([]byte, perShard)
+	}
+
+	rand.Seed(0)
+	for s := 0; s < 10; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Parity from the assembly path must verify using the Go path.
+	ok, err := rGo.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("Verification failed")
+	}
+}
+
+func TestCodeProperties(t *testing.T) {
+	for _, test := range []struct {
+		data, parity int
+		overhead     float64
+	}{
+		{10, 4, 0.4}, {6, 3, 0.5}, {1, 2, 2}, {5, 0, 0},
+	} {
+		r, err := newExt(test.data, test.parity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.StorageOverhead(); got != test.overhead {
+			t.Errorf("%d+%d: expected overhead %v, got %v", test.data, test.parity, test.overhead, got)
+		}
+		if got := r.RepairDegree(); got != test.data {
+			t.Errorf("%d+%d: expected repair degree %d, got %d", test.data, test.parity, test.data, got)
+		}
+		if got := r.FaultTolerance(); got != test.parity {
+			t.Errorf("%d+%d: expected fault tolerance %d, got %d", test.data, test.parity, test.parity, got)
+		}
+		err = r.Reconfigure(4, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.StorageOverhead() != 1 || r.RepairDegree() != 4 || r.FaultTolerance() != 4 {
+			t.Errorf("properties not updated by Reconfigure")
+		}
+	}
+}
+
+func TestKernelBlockSize(t *testing.T) {
+	for _, n := range []int{-64, 1, 100} {
+		_, err := New(10, 4, WithKernelBlockSize(n))
+		if err != ErrInvalidKernelBlockSize {
+			t.Errorf("size %d: expected %v, got %v", n, ErrInvalidKernelBlockSize, err)
+		}
+	}
+	ref, err := newExt(10, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{100, 50000} {
+		want := ref.AllocAligned(size)
+		for s := 0; s < 10; s++ {
+			fillRandom(want[s])
+		}
+		err = ref.Encode(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range []int{0, 64, 192, 4096} {
+			r, err := newExt(10, 4, WithKernelBlockSize(n))
+			if err != nil {
+				t.Fatal(err)
+			}
+			shards := r.AllocAligned(size)
+			for s := 0; s < 10; s++ {
+				copy(shards[s], want[s])
+			}
+			err = r.Encode(shards)
+			if err != nil {
+				t.Fatal(err)
+			}
+			shards[1], shards[5], shards[12] = nil, nil, nil
+			err = r.Reconstruct(shards)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range shards {
+				if !bytes.Equal(shards[i], want[i]) {
+					t.Errorf("size %d, block %d: shard %d mismatch", size, n, i)
+				}
+			}
+		}
+	}
+}
+
+func TestWithRunner(t *testing.T) {
+	var mu sync.Mutex
+	calls, tasks := 0, 0
+	// Run the tasks sequentially, so no goroutines are started.
+	run := func(t []func()) {
+		mu.Lock()
+		calls++
+		tasks += len(t)
+		mu.Unlock()
+		for _, task := range t {
+			task()
+		}
+	}
+	r, err := newExt(10, 3, WithRunner(run))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := r.AllocAligned(100000)
+	for s := 0; s < 10; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOMAXPROCS(0) > 1 && calls == 0 {
+		t.Error("Encode did not use the runner")
+	}
+	calls = 0
+	ok, err := r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("verification failed")
+	}
+	if calls != 1 || tasks < 2 {
+		t.Errorf("expected Verify to give several tasks to the runner in one call, got %d calls", calls)
+	}
+
+	calls, tasks = 0, 0
+	res, err := r.VerifyMany([][][]byte{shards, shards, shards}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, ok := range res {
+		if !ok {
+			t.Errorf("stripe %d failed verification", i)
+		}
+	}
+	if calls != 1 || tasks != 3 {
+		t.Errorf("expected 3 workers in 1 call to the runner, got %d tasks in %d calls", tasks, calls)
+	}
+}
+
+func TestBackend(t *testing.T) {
+	r, err := newExt(10, 3, WithPureGo(true), WithPureGo(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pure-go"
+	switch {
+	case defaultOptions.useAVX2:
+		want = "avx2"
+	case defaultOptions.useSSSE3:
+		want = "ssse3"
+	case defaultOptions.useNEON:
+		want = "neon"
+	}
+	if got := r.Backend(); got != want {
+		t.Fatalf("got backend %q, want %q", got, want)
+	}
+}
+
+func TestSSSE3(t *testing.T) {
+	if !defaultOptions.useSSSE3 {
+		t.Skip("SSSE3 not available")
+	}
+	r, err := newExt(10, 3, WithAVX2(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Backend(); got != "ssse3" {
+		t.Fatalf("got backend %q, want %q", got, "ssse3")
+	}
+	rGo, err := New(10, 3, WithPureGo(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Odd sizes also exercise the scalar tail.
+	for _, size := range []int{1, 15, 16, 17, 1000, 50003} {
+		shards := r.AllocAligned(size)
+		for s := 0; s < 10; s++ {
+			fillRandom(shards[s])
+		}
+		want := make([][]byte, 13)
+		for i := range want {
+			want[i] = append([]byte{}, shards[i]...)
+		}
+		err = r.Encode(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = rGo.Encode(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range shards {
+			if !bytes.Equal(shards[i], want[i]) {
+				t.Fatalf("size %d: shard %d does not match pure Go output", size, i)
+			}
+		}
+	}
+
+	r, err = newExt(10, 3, WithAVX2(false), WithSSSE3(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Backend(); got != "pure-go" {
+		t.Fatalf("got backend %q, want %q", got, "pure-go")
+	}
+}
+
+// backendOptions returns options for each Galois multiplication
+// implementation that is available on this platform.
+func backendOptions() []options {
+	o := []options{{}}
+	if defaultOptions.useSSSE3 {
+		o = append(o, options{useSSSE3: true})
+	}
+	if defaultOptions.useAVX2 {
+		o = append(o, options{useSSSE3: true, useAVX2: true})
+	}
+	if defaultOptions.useNEON {
+		o = append(o, options{useNEON: true})
+	}
+	return o
+}
+
+// conformanceCRC encodes a fixed set of shards with the given backend,
+// and returns the CRC32 of all parity produced.
+func conformanceCRC(t *testing.T, o *options, cauchy bool) uint32 {
+	crc := crc32.NewIEEE()
+	for _, size := range []int{1, 15, 16, 17, 31, 32, 33, 100, 1000, 10000} {
+		var opts []Option
+		if cauchy {
+			opts = append(opts, WithCauchyMatrix())
+		}
+		enc, err := New(17, 3, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := enc.(*reedSolomon)
+		r.o.useAVX2, r.o.useSSSE3, r.o.useNEON = o.useAVX2, o.useSSSE3, o.useNEON
+		shards := createSlice(20, size)
+		for i := 0; i < 17; i++ {
+			for j := range shards[i] {
+				shards[i][j] = byte(i*251 + j*j + j>>8)
+			}
+		}
+		err = r.Encode(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range shards[17:] {
+			crc.Write(p)
+		}
+	}
+	return crc.Sum32()
+}
+
+// TestConformance checks that all backends produce the same
+// parity, and that it matches a known value.
+func TestConformance(t *testing.T) {
+	const want, wantCauchy = 0x7c47a78c, 0x6da7652a
+	for _, o := range backendOptions() {
+		if got := conformanceCRC(t, &o, false); got != want {
+			t.Errorf("%s: got crc %#x, want %#x", o.backend(), got, want)
+		}
+		if got := conformanceCRC(t, &o, true); got != wantCauchy {
+			t.Errorf("%s, cauchy: got crc %#x, want %#x", o.backend(), got, wantCauchy)
+		}
+	}
+}
+
+func TestReconstruct(t *testing.T) {
+	perShard := 50000
+	r, err := New(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := make([][]byte, 13)
+	for s := range shards {
+		shards[s] = make([]byte, perShard)
+	}
+
+	rand.Seed(0)
+	for s := 0; s < 13; s++ {
+		fillRandom(shards[s])
+	}
+
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reconstruct with all shards present
+	err = r.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// This should be a no-op.
+	allocs := testing.AllocsPerRun(10, func() {
+		r.Reconstruct(shards)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+
+	// Reconstruct with 10 shards present
+	shards[0] = nil
+	shards[7] = nil
+	shards[11] = nil
+
+	err = r.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("Verification failed")
+	}
+
+	// Reconstruct with 9 shards present (should fail)
+	shards[0] = nil
+	shards[4] = nil
+	shards[7] = nil
+	shards[11] = nil
+
+	err = r.Reconstruct(shards)
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+
+	err = r.Reconstruct(make([][]byte, 1))
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+	err = r.Reconstruct(make([][]byte, 13))
+	if err != ErrShardNoData {
+		t.Errorf("expected %v, got %v", ErrShardNoData, err)
+	}
+}
+
+func TestReconstructPlan(t *testing.T) {
+	var plans []ReconstructPlan
+	r, err := newExt(5, 3, WithReconstructPlan(func(p ReconstructPlan) {
+		plans = append(plans, p)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := r.AllocAligned(100)
+	for s := 0; s < 5; s++ {
+		fillRandom(orig[s])
+	}
+	err = r.Encode(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, missing := range [][]int{{2}, {2, 6}, {2, 5}, {0, 4, 7}, {6, 7}, {1, 3, 5}, {2}, {2, 5}} {
+		plans = plans[:0]
+		shards := append([][]byte{}, orig...)
+		for _, i := range missing {
+			shards[i] = nil
+		}
+		err = r.Reconstruct(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plans) != 1 {
+			t.Fatalf("missing %v: expected 1 plan, got %d", missing, len(plans))
+		}
+		p := plans[0]
+		if fmt.Sprint(p.Targets) != fmt.Sprint(missing) || len(p.Sources) != 5 || len(p.Matrix) != len(missing) {
+			t.Errorf("missing %v: unexpected plan %+v", missing, p)
+			continue
+		}
+		for i, row := range p.Matrix {
+			// Apply the plan to the sources.
+			got := make([]byte, 100)
+			for k, src := range p.Sources {
+				if contains(missing, src) {
+					t.Errorf("missing %v: shard %d used as source", missing, src)
+				}
+				for n := range got {
+					got[n] ^= galMultiply(row[k], orig[src][n])
+				}
+			}
+			if !bytes.Equal(got, orig[p.Targets[i]]) {
+				t.Errorf("missing %v: plan does not give shard %d", missing, p.Targets[i])
+			}
+			// The plan must be a copy, so this does not change the
+			// plans of the repeated patterns.
+			for k := range row {
+				row[k] = 0
+			}
+		}
+	}
+
+	// Only requested shards are listed.
+	plans = plans[:0]
+	shards := append([][]byte{}, orig...)
+	shards[1], shards[6] = nil, nil
+	err = r.ReconstructData(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 || fmt.Sprint(plans[0].Targets) != "[1]" {
+		t.Errorf("unexpected plans %+v", plans)
+	}
+
+	// Nothing is reported without reconstruction, or for trials.
+	plans = plans[:0]
+	err = r.Reconstruct(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := append([][]byte{}, orig...)
+	bad[3] = append([]byte{}, orig[3]...)
+	bad[3][0] ^= 1
+	_, _, err = r.VerifyShards(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.DetectFailures(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Correct(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 0 {
+		t.Errorf("expected no plans, got %+v", plans)
+	}
+}
+
+func TestReconstructSingle(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithCauchyMatrix()}, {WithNonSystematic()}, {WithNibbleField()}} {
+		r, err := newExt(7, 3, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := r.AllocAligned(5000)
+		for s := 0; s < 7; s++ {
+			fillRandom(shards[s])
+		}
+		err = r.Encode(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 7; i++ {
+			for _, other := range []int{-1, 7, 9} {
+				// The general path is used when the first parity is missing.
+				test := append([][]byte{}, shards...)
+				test[i] = nil
+				if other >= 0 {
+					test[other] = nil
+				}
+				err = r.Reconstruct(test)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for j := range shards {
+					if !bytes.Equal(test[j], shards[j]) {
+						t.Errorf("missing %d and %d: shard %d mismatch", i, other, j)
+					}
+				}
+			}
+		}
+	}
+
+	// A buffer with the capacity is not written by default.
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := r.AllocAligned(500)
+	for s := 0; s < 10; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{}, shards[4]...)
+	buf := make([]byte, 500)
+	shards[4] = buf[:0]
+	err = r.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shards[4], want) || !bytes.Equal(buf, make([]byte, 500)) {
+		t.Error("shard was recreated in place without WithReuseShards")
+	}
+	shards[4] = buf[:0]
+	err = r.ReconstructData(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shards[4], want) || !bytes.Equal(buf, make([]byte, 500)) {
+		t.Error("data shard was recreated in place without WithReuseShards")
+	}
+
+	// With WithReuseShards, it is filled in place.
+	r, err = newExt(10, 3, WithReuseShards())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf = shards[4]
+	allocs := testing.AllocsPerRun(10, func() {
+		shards[4] = buf[:0]
+		err = r.Reconstruct(shards)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shards[4], want) || &shards[4][0] != &buf[0] {
+		t.Error("shard was not recreated in place")
+	}
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestErrorsIs(t *testing.T) {
+	r, err := newExt(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards, err := r.Split(make([]byte, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Encode(shards[:5])
+	if !errors.Is(err, ErrShardCount) || !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+	err = r.Join(ioutil.Discard, shards[:3], 1000)
+	if !errors.Is(err, ErrShardCount) {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+	err = r.Reconstruct(shards, 6)
+	if !errors.Is(err, ErrInvalidShardIndex) {
+		t.Errorf("expected %v, got %v", ErrInvalidShardIndex, err)
+	}
+
+	missing := append([][]byte{}, shards...)
+	missing[0], missing[2], missing[5] = nil, nil, nil
+	err = r.Reconstruct(missing)
+	if !errors.Is(err, ErrTooManyFailures) || errors.Is(err, ErrShardCount) {
+		t.Errorf("expected %v, got %v", ErrTooManyFailures, err)
+	}
+	err = r.ReconstructData(missing)
+	if !errors.Is(err, ErrTooManyFailures) {
+		t.Errorf("expected %v, got %v", ErrTooManyFailures, err)
+	}
+	var tmf TooManyFailuresError
+	if !errors.As(err, &tmf) {
+		t.Fatalf("expected TooManyFailuresError, got %T", err)
+	}
+	if fmt.Sprint(tmf.Missing) != "[0 2 5]" || tmf.Required != 4 {
+		t.Errorf("unexpected error content: %+v", tmf)
+	}
+	for i := range missing {
+		if (i == 0 || i == 2 || i == 5) != (missing[i] == nil) {
+			t.Fatal("shards were modified")
+		}
+	}
+
+	missing[0], missing[2] = shards[0], shards[2][:10]
+	err = r.Reconstruct(missing)
+	if !errors.Is(err, ErrShardSize) {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+
+	_, err = InvertMatrix([][]byte{{1, 1}, {1, 1}})
+	if !errors.Is(err, ErrSingular) {
+		t.Errorf("expected %v, got %v", ErrSingular, err)
+	}
+}
+
+// Run with -race to check that a shared encoder is safe to use.
+func TestConcurrent(t *testing.T) {
+	r, err := New(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			data := make([]byte, 10000+g*1000)
+			fillRandom(data)
+			for i := 0; i < 10; i++ {
+				shards, err := r.Split(data)
+				if err == nil {
+					err = r.Encode(shards)
+				}
+				if err == nil {
+					shards[g%13], shards[(g+5)%13] = nil, nil
+					err = r.Reconstruct(shards)
+				}
+				if err == nil {
+					var ok bool
+					ok, err = r.Verify(shards)
+					if err == nil && !ok {
+						err = errors.New("verification failed")
+					}
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestReconstructSome(t *testing.T) {
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 10000)
+	fillRandom(data)
+	shards, err := r.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := append([][]byte{}, shards...)
+	test[1], test[4], test[12] = nil, nil, nil
+	required := make([]bool, 13)
+	required[4] = true
+	err = r.ReconstructSome(test, required)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(test[4], shards[4]) {
+		t.Fatal("shard 4 mismatch")
+	}
+	if test[1] != nil || test[12] != nil {
+		t.Fatal("shards that were not required were reconstructed")
+	}
+
+	// Nothing required.
+	err = r.ReconstructSome(test, make([]bool, 13))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if test[1] != nil || test[12] != nil {
+		t.Fatal("shards that were not required were reconstructed")
+	}
+
+	required[12] = true
+	err = r.ReconstructSome(test, required)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(test[12], shards[12]) || !bytes.Equal(test[1], shards[1]) {
+		t.Fatal("parity shard mismatch")
+	}
+
+	err = r.ReconstructSome(test, required[:12])
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+	test[0], test[1], test[2], test[3] = nil, nil, nil, nil
+	required[0] = true
+	err = r.ReconstructSome(test, required)
+	if !errors.Is(err, ErrTooManyFailures) {
+		t.Errorf("expected %v, got %v", ErrTooManyFailures, err)
+	}
+}
+
+func TestTranscode(t *testing.T) {
+	type scheme struct {
+		data, parity int
+		opts         []Option
+	}
+	tests := []struct{ src, dst scheme }{
+		{src: scheme{6, 2, nil}, dst: scheme{10, 4, nil}},
+		{src: scheme{10, 4, nil}, dst: scheme{6, 2, nil}},
+		{src: scheme{5, 3, nil}, dst: scheme{5, 3, []Option{WithCauchyMatrix()}}},
+		{src: scheme{4, 2, nil}, dst: scheme{7, 3, []Option{WithNonSystematic()}}},
+		{src: scheme{4, 2, []Option{WithNonSystematic()}}, dst: scheme{3, 1, nil}},
+	}
+	data := make([]byte, 10001)
+	fillRandom(data)
+	for _, test := range tests {
+		src, err := newExt(test.src.data, test.src.parity, test.src.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst, err := newExt(test.dst.data, test.dst.parity, test.dst.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards, err := src.Split(append([]byte{}, data...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = src.Encode(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A missing shard with capacity must not be written into.
+		spare := make([]byte, len(shards[0]))
+		shards[0], shards[len(shards)-1] = spare[:0], nil
+
+		out, err := src.Transcode(shards, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(shards[0]) != 0 || shards[len(shards)-1] != nil {
+			t.Fatal("input shards were modified")
+		}
+		if !bytes.Equal(spare, make([]byte, len(spare))) {
+			t.Fatal("missing shard buffer was written")
+		}
+		if len(out) != test.dst.data+test.dst.parity {
+			t.Fatalf("%v: expected %d shards, got %d", test, test.dst.data+test.dst.parity, len(out))
+		}
+		ok, err := dst.Verify(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("%v: verification failed", test)
+		}
+		err = dst.DecodeData(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		err = dst.Join(&buf, out, len(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Fatalf("%v: transcoded data mismatch", test)
+		}
+	}
+
+	src, _ := newExt(4, 2)
+	shards := src.AllocAligned(100)
+	_, err := src.Transcode(shards, nil)
+	if err != ErrInvalidEncoder {
+		t.Errorf("expected %v, got %v", ErrInvalidEncoder, err)
+	}
+	dst, _ := New(3, 1)
+	_, err = src.Transcode(shards[:5], dst)
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+	shards[0], shards[1], shards[2] = nil, nil, nil
+	_, err = src.Transcode(shards, dst)
+	if !errors.Is(err, ErrTooManyFailures) {
+		t.Errorf("expected %v, got %v", ErrTooManyFailures, err)
+	}
+}
+
+func TestReconstructIndexed(t *testing.T) {
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 10000)
+	fillRandom(data)
+	shards, err := r.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[int][]byte)
+	perm := rand.Perm(13)
+	for _, idx := range perm[:10] {
+		got[idx] = shards[idx]
+	}
+	all, err := r.ReconstructIndexed(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 10 {
+		t.Fatal("map was modified")
+	}
+	for i := range shards {
+		if !bytes.Equal(all[i], shards[i]) {
+			t.Fatal("shard mismatch at index", i)
+		}
+	}
+
+	delete(got, perm[0])
+	_, err = r.ReconstructIndexed(got)
+	if !errors.Is(err, ErrTooManyFailures) {
+		t.Errorf("expected %v, got %v", ErrTooManyFailures, err)
+	}
+	got[13] = shards[0]
+	_, err = r.ReconstructIndexed(got)
+	if !errors.Is(err, ErrInvalidShardIndex) {
+		t.Errorf("expected %v, got %v", ErrInvalidShardIndex, err)
+	}
+}
+
+func TestReconstructStats(t *testing.T) {
+	r, err := newExt(4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards, err := r.Split(make([]byte, 4000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		missing []int
+		idxs    []int
+		read    int
+	}{
+		{missing: nil, read: 0},
+		{missing: []int{1}, read: 4},
+		{missing: []int{5, 6}, read: 4},
+		{missing: []int{0, 1}, idxs: []int{2}, read: 0},
+		{missing: []int{0, 6}, idxs: []int{6}, read: 4},
+	} {
+		set := append([][]byte{}, shards...)
+		for _, idx := range test.missing {
+			set[idx] = nil
+		}
+		st, err := r.ReconstructStats(set, test.idxs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.ShardsRead != test.read || st.BytesRead != test.read*len(shards[0]) {
+			t.Errorf("missing %v, idxs %v: got %+v, expected %d shards read", test.missing, test.idxs, st, test.read)
+		}
+	}
+	set := append([][]byte{}, shards...)
+	set[0], set[1], set[2], set[3] = nil, nil, nil, nil
+	st, err := r.ReconstructStats(set)
+	if !errors.Is(err, ErrTooManyFailures) || st != (ReadStats{}) {
+		t.Errorf("expected %v and no stats, got %v, %+v", ErrTooManyFailures, err, st)
+	}
+}
+
+func TestDetectFailures(t *testing.T) {
+	r, err := newExt(10, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := r.AllocAligned(1000)
+	for s := 0; s < 10; s++ {
+		fillRandom(orig[s])
+	}
+	err = r.Encode(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt := func(b []byte) []byte {
+		b = append([]byte{}, b...)
+		b[500] ^= 0x55
+		return b
+	}
+	marked := func(failed []bool) []int {
+		var idx []int
+		for i, f := range failed {
+			if f {
+				idx = append(idx, i)
+			}
+		}
+		return idx
+	}
+
+	tests := []struct {
+		missing, corrupted []int
+		want               []int
+		err                error
+	}{
+		{},
+		{missing: []int{3, 12}, want: []int{3, 12}},
+		{corrupted: []int{4}, want: []int{4}},
+		{corrupted: []int{11}, want: []int{11}},
+		{missing: []int{0, 13}, corrupted: []int{7}, want: []int{0, 7, 13}},
+		// Exactly DataShards present, so nothing can be detected.
+		{missing: []int{1, 2, 3, 4}, corrupted: []int{5}, want: []int{1, 2, 3, 4}},
+		// One spare shard cannot tell which shard is wrong.
+		{missing: []int{1, 2, 3}, corrupted: []int{5}, want: []int{1, 2, 3}, err: ErrCorruptionNotLocated},
+		{corrupted: []int{2, 9}, err: ErrCorruptionNotLocated},
+	}
+	for _, test := range tests {
+		shards := make([][]byte, len(orig))
+		copy(shards, orig)
+		for _, i := range test.missing {
+			shards[i] = nil
+		}
+		for _, i := range test.corrupted {
+			shards[i] = corrupt(orig[i])
+		}
+		failed, err := r.DetectFailures(shards)
+		if err != test.err {
+			t.Errorf("%+v: expected %v, got %v", test, test.err, err)
+			continue
+		}
+		if got := marked(failed); fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%+v: expected %v marked, got %v", test, test.want, got)
+			continue
+		}
+		if err != nil || len(test.missing) == 4 {
+			continue
+		}
+		if !r.CanRepair(failed) {
+			t.Errorf("%+v: cannot repair %v", test, failed)
+		}
+		for i, f := range failed {
+			if f {
+				shards[i] = nil
+			}
+		}
+		err = r.Reconstruct(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range shards {
+			if !bytes.Equal(shards[i], orig[i]) {
+				t.Errorf("%+v: shard %d not repaired", test, i)
+			}
+		}
+	}
+
+	shards := make([][]byte, len(orig))
+	copy(shards, orig[:9])
+	_, err = r.DetectFailures(shards)
+	if !errors.Is(err, ErrTooManyFailures) {
+		t.Errorf("expected %v, got %v", ErrTooManyFailures, err)
+	}
+	_, err = r.DetectFailures(shards[:5])
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithCauchyMatrix()},
+		{WithNonSystematic()},
+		{WithNibbleField()},
+		{WithFieldPoly(0x12b)},
+	} {
+		testCorrect(t, opts...)
+	}
+
+	r, err := newExt(10, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Correct(make([][]byte, 13))
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+}
+
+func testCorrect(t *testing.T, opts ...Option) {
+	r, err := newExt(10, 4, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := r.AllocAligned(1000)
+	for s := 0; s < 10; s++ {
+		fillRandom(orig[s])
+	}
+	err = r.Encode(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		missing, corrupted []int
+		// Corrupt the same byte of every corrupted shard,
+		// instead of different bytes for every shard.
+		sameByte bool
+		// Corrupt every byte of the corrupted shards.
+		whole bool
+		err   error
+	}{
+		{},
+		{missing: []int{3, 12}},
+		{corrupted: []int{4}},
+		{corrupted: []int{4}, whole: true},
+		{corrupted: []int{2, 9}},
+		{corrupted: []int{2, 9}, sameByte: true},
+		{corrupted: []int{0, 13}, whole: true},
+		{missing: []int{5, 6}, corrupted: []int{11}},
+		{missing: []int{5, 6}, corrupted: []int{11}, whole: true},
+		{missing: []int{5}, corrupted: []int{1}},
+		// Only one shard is corrupted for every byte.
+		{corrupted: []int{1, 2, 3}},
+		{corrupted: []int{0, 5, 10, 13}},
+		// Too many for the parity left.
+		{missing: []int{5, 6, 7}, corrupted: []int{1}, err: ErrCorruptionNotLocated},
+		{corrupted: []int{1, 2, 3}, sameByte: true, err: ErrCorruptionNotLocated},
+		{missing: []int{8, 9}, corrupted: []int{1, 2}, sameByte: true, err: ErrCorruptionNotLocated},
+	}
+	for _, test := range tests {
+		shards := make([][]byte, len(orig))
+		for i := range shards {
+			shards[i] = append([]byte{}, orig[i]...)
+		}
+		for _, i := range test.missing {
+			shards[i] = nil
+		}
+		for n, i := range test.corrupted {
+			switch {
+			case test.whole:
+				for b := range shards[i] {
+					shards[i][b] ^= byte(b) | 1
+				}
+			case test.sameByte:
+				shards[i][100] ^= byte(0x11 << n)
+			default:
+				shards[i][i*50] ^= 0x55
+				shards[i][999-i] ^= 0xaa
+			}
+		}
+		corrupt := make([][]byte, len(shards))
+		for i := range shards {
+			corrupt[i] = append([]byte(nil), shards[i]...)
+		}
+		corrected, err := r.Correct(shards)
+		if err != test.err {
+			t.Errorf("%v %+v: expected %v, got %v", opts, test, test.err, err)
+			continue
+		}
+		if err != nil {
+			for i := range shards {
+				if !bytes.Equal(shards[i], corrupt[i]) {
+					t.Errorf("%v %+v: shard %d was modified", opts, test, i)
+				}
+			}
+			continue
+		}
+		if fmt.Sprint(corrected) != fmt.Sprint(test.corrupted) {
+			t.Errorf("%v %+v: expected %v corrected, got %v", opts, test, test.corrupted, corrected)
+		}
+		for i := range shards {
+			if !bytes.Equal(shards[i], orig[i]) {
+				t.Errorf("%v %+v: shard %d mismatch", opts, test, i)
+			}
+		}
+	}
+}
+
+func TestCheckMatrix(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithCauchyMatrix()},
+		{WithNonSystematic()},
+		{WithNibbleField()},
+		{WithFieldPoly(0x12b)},
+	} {
+		enc, err := New(6, 5, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := enc.(*reedSolomon)
+		shards := r.AllocAligned(100)
+		for s := 0; s < 6; s++ {
+			fillRandom(shards[s])
+		}
+		err = r.Encode(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, present := range [][]int{
+			{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			{0, 2, 3, 4, 5, 6, 10},
+			{1, 3, 4, 5, 7, 8, 9, 10},
+			{0, 1, 2, 3, 4, 5, 6},
+		} {
+			h, _, err := r.checkMatrix(present)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(h) != len(present)-6 {
+				t.Errorf("%v %v: expected %d checks, got %d", opts, present, len(present)-6, len(h))
+			}
+			synd := make([]byte, 100)
+			for j, row := range h {
+				for k, idx := range present {
+					if k == 0 {
+						galMulSlice(row[k], shards[idx], synd, &r.o)
+					} else {
+						galMulSliceXor(row[k], shards[idx], synd, &r.o)
+					}
+				}
+				if !bytes.Equal(synd, make([]byte, 100)) {
+					t.Errorf("%v %v: check %d is not zero", opts, present, j)
+				}
+			}
+		}
+	}
+}
+
+func TestVerifyReconstruct(t *testing.T) {
+	r, err := newExt(5, 3, WithVerifyReconstruct())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := r.AllocAligned(1000)
+	for s := 0; s < 5; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shards[6][10] ^= 1
+	err = r.Reconstruct(shards)
+	if err != ErrCorruptionNotLocated {
+		t.Errorf("expected %v, got %v", ErrCorruptionNotLocated, err)
+	}
+	err = r.ReconstructData(shards)
+	if err != ErrCorruptionNotLocated {
+		t.Errorf("expected %v, got %v", ErrCorruptionNotLocated, err)
+	}
+
+	// Missing shards are recreated, and not verified.
+	shards[1] = nil
+	err = r.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards[6][10] ^= 1
+	ok, err := r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("verification failed")
+	}
+
+	// Correct does not verify the shards it recreates from.
+	shards[2][20] ^= 1
+	corrected, err := r.Correct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(corrected) != "[2]" {
+		t.Errorf("expected [2] corrected, got %v", corrected)
+	}
+}
+
+func TestReconstructLazy(t *testing.T) {
+	r, err := newExt(5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := r.AllocAligned(1000)
+	for s := 0; s < 5; s++ {
+		fillRandom(orig[s])
+	}
+	err = r.Encode(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetched []int
+	fetch := func(idx int) ([]byte, error) {
+		fetched = append(fetched, idx)
+		return orig[idx], nil
+	}
+	failed := []bool{false, true, false, false, true, false, false, true}
+	shards, err := r.ReconstructLazy(failed, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(fetched) != "[0 2 3 5 6]" {
+		t.Errorf("expected shards [0 2 3 5 6] fetched, got %v", fetched)
+	}
+	for i, f := range failed {
+		if f && !bytes.Equal(shards[i], orig[i]) {
+			t.Errorf("shard %d not recreated", i)
+		}
+	}
+
+	fetched = nil
+	shards, err = r.ReconstructLazy(make([]bool, 8), fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fetched) != 0 || len(shards) != 8 {
+		t.Errorf("expected nothing fetched, got %v", fetched)
+	}
+
+	errFetch := errors.New("unavailable")
+	_, err = r.ReconstructLazy(failed, func(idx int) ([]byte, error) {
+		if idx == 3 {
+			return nil, errFetch
+		}
+		return orig[idx], nil
+	})
+	if !errors.Is(err, errFetch) {
+		t.Errorf("expected %v, got %v", errFetch, err)
+	}
+	_, err = r.ReconstructLazy(failed, func(idx int) ([]byte, error) { return nil, nil })
+	if !errors.Is(err, ErrShardNoData) {
+		t.Errorf("expected %v, got %v", ErrShardNoData, err)
+	}
+	_, err = r.ReconstructLazy([]bool{true, true, true, true, false, false, false, false}, fetch)
+	if !errors.Is(err, ErrTooManyFailures) {
+		t.Errorf("expected %v, got %v", ErrTooManyFailures, err)
+	}
+	_, err = r.ReconstructLazy(failed[:3], fetch)
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+}
+
+func TestMinimalSet(t *testing.T) {
+	r, err := newExt(4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards, err := r.Split(make([]byte, 400))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fillRandom(shards[0])
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	available := []bool{true, false, true, false, true, true, true}
+	set, err := r.MinimalSet(available)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(set) != "[0 2 4 5]" {
+		t.Fatalf("unexpected set %v", set)
+	}
+
+	// Only the minimal set is needed to reconstruct.
+	test := make([][]byte, 7)
+	for _, idx := range set {
+		test[idx] = shards[idx]
+	}
+	err = r.Reconstruct(test)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range shards {
+		if !bytes.Equal(test[i], shards[i]) {
+			t.Fatalf("shard %d mismatch", i)
+		}
+	}
+
+	_, err = r.MinimalSet([]bool{true, false, true, false, false, true, false})
+	if !errors.Is(err, ErrTooManyFailures) {
+		t.Errorf("expected %v, got %v", ErrTooManyFailures, err)
+	}
+	_, err = r.MinimalSet(available[:6])
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+}
+
+func TestCanReconstruct(t *testing.T) {
+	r, err := newExt(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards, err := r.Split(make([]byte, 400))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Check every pattern against Reconstruct.
+	for mask := 0; mask < 1<<6; mask++ {
+		present := make([]bool, 6)
+		failed := make([]bool, 6)
+		test := make([][]byte, 6)
+		for i := range present {
+			present[i] = mask&(1<<uint(i)) != 0
+			failed[i] = !present[i]
+			if present[i] {
+				test[i] = shards[i]
+			}
+		}
+		err := r.Reconstruct(test)
+		if mask == 0 {
+			// No data at all is a different error.
+			err = ErrTooManyFailures
+		}
+		can := r.CanReconstruct(present)
+		if can != (err == nil) {
+			t.Errorf("mask %06b: CanReconstruct %v, Reconstruct returned %v", mask, can, err)
+		}
+		if r.CanRepair(failed) != can {
+			t.Errorf("mask %06b: CanRepair does not match CanReconstruct", mask)
+		}
+	}
+	if r.CanReconstruct(make([]bool, 5)) || r.CanRepair(make([]bool, 7)) {
+		t.Error("expected false for wrong length")
+	}
+}
+
+func TestLazyParity(t *testing.T) {
+	r, err := newExt(6, 4, WithLazyParity())
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := r.AllocAligned(1000)
+	for s := 0; s < 6; s++ {
+		fillRandom(orig[s])
+	}
+	err = r.Encode(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, idxs := range [][]int{nil, {7}, {1, 9}, {6, 7, 8, 9}} {
+		shards := make([][]byte, len(orig))
+		copy(shards, orig)
+		shards[1], shards[3], shards[7], shards[9] = nil, nil, nil, nil
+		err = r.Reconstruct(shards, idxs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range shards {
+			missing := i == 7 || i == 9
+			want := i < 6 || !missing || contains(idxs, i)
+			if !want {
+				if shards[i] != nil {
+					t.Errorf("%v: parity %d was recreated", idxs, i)
+				}
+				continue
+			}
+			if !bytes.Equal(shards[i], orig[i]) {
+				t.Errorf("%v: shard %d mismatch", idxs, i)
+			}
+		}
+	}
+
+	shards := make([][]byte, len(orig))
+	copy(shards, orig)
+	shards[0] = nil
+	err = r.Reconstruct(shards, 10)
+	if !errors.Is(err, ErrInvalidShardIndex) {
+		t.Errorf("expected %v, got %v", ErrInvalidShardIndex, err)
+	}
+}
+
+func TestReconstructData(t *testing.T) {
+	perShard := 100000
+	r, err := newExt(8, 5)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -77,45 +1944,55 @@ func TestReconstruct(t *testing.T) {
 	}
 
 	// Reconstruct with all shards present
-	err = r.Reconstruct(shards)
+	err = r.ReconstructData(shards)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Reconstruct with 10 shards present
+	shard0 := shards[0]
+	shard7 := shards[7]
 	shards[0] = nil
 	shards[7] = nil
 	shards[11] = nil
 
-	err = r.Reconstruct(shards)
+	err = r.ReconstructData(shards)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if !bytes.Equal(shard0, shards[0]) || !bytes.Equal(shard7, shards[7]) {
+		t.Fatal("reconstructed data mismatch")
+	}
+	if shards[11] != nil {
+		t.Fatal("ReconstructData reconstructed parity shard")
+	}
 
-	ok, err := r.Verify(shards)
+	// Only parity missing, nothing should be done.
+	err = r.ReconstructData(shards)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !ok {
-		t.Fatal("Verification failed")
+	if shards[11] != nil {
+		t.Fatal("ReconstructData reconstructed parity shard")
 	}
 
-	// Reconstruct with 9 shards present (should fail)
+	// Reconstruct with 7 shards present (should fail)
 	shards[0] = nil
 	shards[4] = nil
 	shards[7] = nil
-	shards[11] = nil
+	shards[10] = nil
+	shards[12] = nil
 
-	err = r.Reconstruct(shards)
-	if err != ErrTooFewShards {
+	err = r.ReconstructData(shards)
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
 
-	err = r.Reconstruct(make([][]byte, 1))
-	if err != ErrTooFewShards {
+	err = r.ReconstructData(make([][]byte, 1))
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
-	err = r.Reconstruct(make([][]byte, 13))
+	err = r.ReconstructData(make([][]byte, 13))
 	if err != ErrShardNoData {
 		t.Errorf("expected %v, got %v", ErrShardNoData, err)
 	}
@@ -161,117 +2038,323 @@ func TestReconstructWithIndexes(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// Check that parity shards do not get reconstructed
-	shard0 := shards[0]
-	shard2 := shards[2]
-	shards[0] = nil
-	shards[2] = nil
-	shards[11] = nil
-	shards[12] = nil
+	// Check that parity shards do not get reconstructed
+	shard0 := shards[0]
+	shard2 := shards[2]
+	shards[0] = nil
+	shards[2] = nil
+	shards[11] = nil
+	shards[12] = nil
+
+	err = r.Reconstruct(shards, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Compare(shard0, shards[0]) != 0 ||
+		bytes.Compare(shard2, shards[2]) != 0 {
+		t.Errorf("Shard content mismatch")
+	}
+
+	if shards[11] != nil ||
+		shards[12] != nil {
+		t.Errorf("unspecified shard reconstructed.")
+	}
+
+	// Reset all shards
+	err = r.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check that only required parity shard is reconstructed.
+	shard0 = shards[0]
+	shard2 = shards[2]
+	shard12 := shards[12]
+	shards[0] = nil
+	shards[2] = nil
+	shards[11] = nil
+	shards[12] = nil
+
+	err = r.Reconstruct(shards, 0, 2, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Compare(shard0, shards[0]) != 0 ||
+		bytes.Compare(shard2, shards[2]) != 0 ||
+		bytes.Compare(shard12, shards[12]) != 0 {
+		t.Errorf("Shard content mismatch")
+	}
+
+	if shards[11] != nil {
+		t.Errorf("unspecified shard reconstructed. shard11")
+	}
+
+	// Reset all shards
+	err = r.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check out of range case
+	err = r.Reconstruct(shards, totalShards)
+	if err == nil {
+		t.Fatal("out of range case. Error expected")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	perShard := 33333
+	r, err := New(10, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := make([][]byte, 14)
+	for s := range shards {
+		shards[s] = make([]byte, perShard)
+	}
+
+	rand.Seed(0)
+	for s := 0; s < 10; s++ {
+		fillRandom(shards[s])
+	}
+
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("Verification failed")
+	}
+
+	// Put in random data. Verification should fail
+	fillRandom(shards[10])
+	ok, err = r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("Verification did not fail")
+	}
+	// Re-encode
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fill a data segment with random data
+	fillRandom(shards[0])
+	ok, err = r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("Verification did not fail")
+	}
+
+	_, err = r.Verify(make([][]byte, 1))
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+
+	_, err = r.Verify(make([][]byte, 14))
+	if err != ErrShardNoData {
+		t.Errorf("expected %v, got %v", ErrShardNoData, err)
+	}
+
+	_, err = r.Verify(append(shards, shards[0]))
+	var cerr ShardCountError
+	if !errors.As(err, &cerr) || !errors.Is(err, ErrShardCount) {
+		t.Fatalf("expected %T, got %v", cerr, err)
+	}
+	if cerr.Got != 15 || cerr.Want != 14 {
+		t.Errorf("expected 15 shards given and 14 wanted, got %d and %d", cerr.Got, cerr.Want)
+	}
+
+	for _, short := range []int{0, 5, 13} {
+		shards[short] = shards[short][:perShard-1]
+		_, err = r.Verify(shards)
+		shards[short] = shards[short][:perShard]
+		var serr ShardSizeError
+		if !errors.As(err, &serr) || !errors.Is(err, ErrShardSize) {
+			t.Fatalf("expected %T, got %v", serr, err)
+		}
+		// The first shard sets the size, so a short first shard
+		// makes the second shard the offending one.
+		want := ShardSizeError{Shard: short, Size: perShard - 1, Want: perShard}
+		if short == 0 {
+			want = ShardSizeError{Shard: 1, Size: perShard, Want: perShard - 1}
+		}
+		if serr != want {
+			t.Errorf("expected %+v, got %+v", want, serr)
+		}
+	}
+	shards[3] = nil
+	_, err = r.Verify(shards)
+	if want := (ShardSizeError{Shard: 3, Size: 0, Want: perShard}); err != want {
+		t.Errorf("expected %v, got %v", want, err)
+	}
+}
+
+func TestVerifyShards(t *testing.T) {
+	r, err := newExt(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 10000)
+	fillRandom(data)
+	shards, err := r.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, bad, err := r.VerifyShards(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || bad != nil {
+		t.Fatalf("expected ok, got %v %v", ok, bad)
+	}
+
+	for _, idx := range []int{0, 7, 10, 12} {
+		shards[idx][55] ^= 0x40
+		ok, bad, err = r.VerifyShards(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok || len(bad) != 1 || bad[0] != idx {
+			t.Errorf("corrupted %d: got %v %v", idx, ok, bad)
+		}
+		shards[idx][55] ^= 0x40
+	}
 
-	err = r.Reconstruct(shards, 0, 2)
+	// Two corrupted data shards cannot be located with 3 parity shards,
+	// but all the parity will mismatch.
+	shards[1][0] ^= 1
+	shards[2][0] ^= 1
+	ok, bad, err = r.VerifyShards(shards)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	if bytes.Compare(shard0, shards[0]) != 0 ||
-		bytes.Compare(shard2, shards[2]) != 0 {
-		t.Errorf("Shard content mismatch")
+	if ok || len(bad) != 3 || bad[0] != 10 {
+		t.Errorf("expected all parity shards, got %v %v", ok, bad)
 	}
+	shards[1][0] ^= 1
+	shards[2][0] ^= 1
 
-	if shards[11] != nil ||
-		shards[12] != nil {
-		t.Errorf("unspecified shard reconstructed.")
+	// With one parity shard, only the parity can be reported.
+	r, err = newExt(4, 1)
+	if err != nil {
+		t.Fatal(err)
 	}
-
-	// Reset all shards
-	err = r.Reconstruct(shards)
+	shards, err = r.Split(data)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	// Check that only required parity shard is reconstructed.
-	shard0 = shards[0]
-	shard2 = shards[2]
-	shard12 := shards[12]
-	shards[0] = nil
-	shards[2] = nil
-	shards[11] = nil
-	shards[12] = nil
-
-	err = r.Reconstruct(shards, 0, 2, 12)
+	err = r.Encode(shards)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	if bytes.Compare(shard0, shards[0]) != 0 ||
-		bytes.Compare(shard2, shards[2]) != 0 ||
-		bytes.Compare(shard12, shards[12]) != 0 {
-		t.Errorf("Shard content mismatch")
+	shards[0][0] ^= 1
+	ok, bad, err = r.VerifyShards(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok || len(bad) != 1 || bad[0] != 4 {
+		t.Errorf("expected parity shard, got %v %v", ok, bad)
 	}
 
-	if shards[11] != nil {
-		t.Errorf("unspecified shard reconstructed. shard11")
+	_, _, err = r.VerifyShards(shards[:4])
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
 	}
+}
 
-	// Reset all shards
-	err = r.Reconstruct(shards)
+func TestVerifyMany(t *testing.T) {
+	r, err := newExt(10, 3)
 	if err != nil {
 		t.Fatal(err)
 	}
+	stripes := make([][][]byte, 50)
+	for i := range stripes {
+		data := make([]byte, 1000+i*100)
+		fillRandom(data)
+		stripes[i], err = r.Split(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.Encode(stripes[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	stripes[7][2][5] ^= 1
+	stripes[49][12][0] ^= 1
+	for _, workers := range []int{0, 1, 3, 100} {
+		ok, err := r.VerifyMany(stripes, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ok) != len(stripes) {
+			t.Fatalf("expected %d results, got %d", len(stripes), len(ok))
+		}
+		for i := range ok {
+			if ok[i] != (i != 7 && i != 49) {
+				t.Errorf("workers %d: stripe %d: got %v", workers, i, ok[i])
+			}
+		}
+	}
 
-	// Check out of range case
-	err = r.Reconstruct(shards, totalShards)
-	if err == nil {
-		t.Fatal("out of range case. Error expected")
+	stripes[20] = stripes[20][:12]
+	_, err = r.VerifyMany(stripes, 0)
+	if !errors.Is(err, ErrShardCount) {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+	ok, err := r.VerifyMany(nil, 0)
+	if err != nil || len(ok) != 0 {
+		t.Errorf("expected no results, got %v, %v", ok, err)
 	}
 }
 
-func TestVerify(t *testing.T) {
+func TestVerifyInto(t *testing.T) {
 	perShard := 33333
-	r, err := New(10, 4)
+	r, err := newExt(10, 4)
 	if err != nil {
 		t.Fatal(err)
 	}
-	shards := make([][]byte, 14)
-	for s := range shards {
-		shards[s] = make([]byte, perShard)
-	}
-
+	shards := createSlice(14, perShard)
 	rand.Seed(0)
 	for s := 0; s < 10; s++ {
 		fillRandom(shards[s])
 	}
-
 	err = r.Encode(shards)
 	if err != nil {
 		t.Fatal(err)
 	}
-	ok, err := r.Verify(shards)
+	scratch := createSlice(4, perShard+10)
+	ok, err := r.VerifyInto(shards, scratch)
 	if err != nil {
 		t.Fatal(err)
 	}
 	if !ok {
 		t.Fatal("Verification failed")
 	}
-
-	// Put in random data. Verification should fail
-	fillRandom(shards[10])
-	ok, err = r.Verify(shards)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if ok {
-		t.Fatal("Verification did not fail")
-	}
-	// Re-encode
-	err = r.Encode(shards)
-	if err != nil {
-		t.Fatal(err)
+	allocs := testing.AllocsPerRun(10, func() {
+		r.VerifyInto(shards, scratch)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
 	}
-	// Fill a data segment with random data
-	fillRandom(shards[0])
-	ok, err = r.Verify(shards)
+
+	shards[12][perShard-1]++
+	ok, err = r.VerifyInto(shards, scratch)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -279,14 +2362,14 @@ func TestVerify(t *testing.T) {
 		t.Fatal("Verification did not fail")
 	}
 
-	_, err = r.Verify(make([][]byte, 1))
-	if err != ErrTooFewShards {
+	_, err = r.VerifyInto(shards, scratch[:3])
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
-
-	_, err = r.Verify(make([][]byte, 14))
-	if err != ErrShardNoData {
-		t.Errorf("expected %v, got %v", ErrShardNoData, err)
+	scratch[2] = scratch[2][:perShard-1]
+	_, err = r.VerifyInto(shards, scratch)
+	if err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
 	}
 }
 
@@ -342,6 +2425,92 @@ func TestOneEncode(t *testing.T) {
 
 }
 
+func TestCauchyEncode(t *testing.T) {
+	codec, err := newExt(5, 5, WithCauchyMatrix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Parity row r, column c is 1/(r XOR c).
+	expect := "[[167, 71, 186, 122, 1]," +
+		" [122, 186, 71, 167, 142]," +
+		" [186, 122, 167, 71, 244]," +
+		" [173, 157, 221, 152, 61]," +
+		" [157, 173, 152, 221, 170]]"
+	if str := matrix(codec.Matrix()).String(); str != expect {
+		t.Fatal(str, "!=", expect)
+	}
+	shards := [][]byte{
+		{0, 1},
+		{4, 5},
+		{2, 3},
+		{6, 7},
+		{8, 9},
+		{0, 0},
+		{0, 0},
+		{0, 0},
+		{0, 0},
+		{0, 0},
+	}
+	err = codec.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parity := [][]byte{
+		{97, 64},
+		{173, 3},
+		{218, 14},
+		{107, 35},
+		{110, 177},
+	}
+	for i, want := range parity {
+		if !bytes.Equal(shards[5+i], want) {
+			t.Fatalf("shard %d mismatch, got %v, want %v", 5+i, shards[5+i], want)
+		}
+	}
+
+	// Any 5 shards must be able to recreate the data.
+	shards[0], shards[2], shards[4], shards[6], shards[8] = nil, nil, nil, nil, nil
+	err = codec.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := codec.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("did not verify")
+	}
+	if !bytes.Equal(shards[2], []byte{2, 3}) {
+		t.Fatal("reconstructed shard 2 mismatch")
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	codec, err := newExt(5, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := codec.Matrix()
+	if len(m) != 5 {
+		t.Fatal("expected 5 rows, got", len(m))
+	}
+	expect := "[[7, 7, 6, 6, 1]," +
+		" [9, 8, 9, 8, 1]," +
+		" [15, 14, 14, 15, 1]," +
+		" [2, 125, 149, 253, 22]," +
+		" [31, 96, 137, 225, 22]]"
+	if str := matrix(m).String(); str != expect {
+		t.Fatal(str, "!=", expect)
+	}
+
+	// Modifying the returned matrix must not change the encoder.
+	m[0][0] ^= 0xff
+	if codec.Matrix()[0][0] == m[0][0] {
+		t.Fatal("Matrix did not return a copy")
+	}
+}
+
 func fillRandom(p []byte) {
 	for i := 0; i < len(p); i += 7 {
 		val := rand.Int63()
@@ -352,6 +2521,15 @@ func fillRandom(p []byte) {
 	}
 }
 
+// newExt functions as New, but returns the Extensions of the encoder.
+func newExt(dataShards, parityShards int, opts ...Option) (Extensions, error) {
+	enc, err := New(dataShards, parityShards, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return enc.(Extensions), nil
+}
+
 func benchmarkEncode(b *testing.B, dataShards, parityShards, shardSize int) {
 	r, err := New(dataShards, parityShards)
 	if err != nil {
@@ -394,40 +2572,228 @@ func BenchmarkEncode10x4x16M(b *testing.B) {
 	benchmarkEncode(b, 10, 4, 16*1024*1024)
 }
 
+// Benchmark 3 data shards and 1 parity shard with 1MB each.
+// The parity row is all ones, so this only uses XOR.
+func BenchmarkEncode3x1x1M(b *testing.B) {
+	benchmarkEncode(b, 3, 1, 1024*1024)
+}
+
+// Benchmark 4 data shards and 1 parity shard with 1MB each.
+func BenchmarkEncode4x1x1M(b *testing.B) {
+	benchmarkEncode(b, 4, 1, 1024*1024)
+}
+
 // Benchmark 5 data shards and 2 parity shards with 1MB each.
 func BenchmarkEncode5x2x1M(b *testing.B) {
 	benchmarkEncode(b, 5, 2, 1024*1024)
 }
 
-// Benchmark 1 data shards and 2 parity shards with 1MB each.
-func BenchmarkEncode10x2x1M(b *testing.B) {
-	benchmarkEncode(b, 10, 2, 1024*1024)
-}
+// Benchmark 1 data shards and 2 parity shards with 1MB each.
+func BenchmarkEncode10x2x1M(b *testing.B) {
+	benchmarkEncode(b, 10, 2, 1024*1024)
+}
+
+// Benchmark 10 data shards and 4 parity shards with 1MB each.
+func BenchmarkEncode10x4x1M(b *testing.B) {
+	benchmarkEncode(b, 10, 4, 1024*1024)
+}
+
+// Benchmark 50 data shards and 20 parity shards with 1MB each.
+func BenchmarkEncode50x20x1M(b *testing.B) {
+	benchmarkEncode(b, 50, 20, 1024*1024)
+}
+
+// Benchmark 17 data shards and 3 parity shards with 16MB each.
+func BenchmarkEncode17x3x16M(b *testing.B) {
+	benchmarkEncode(b, 17, 3, 16*1024*1024)
+}
+
+func benchmarkEncodeKernelBlockSize(b *testing.B, n int) {
+	r, err := newExt(10, 4, WithKernelBlockSize(n))
+	if err != nil {
+		b.Fatal(err)
+	}
+	shards := r.AllocAligned(1024 * 1024)
+	for s := 0; s < 10; s++ {
+		fillRandom(shards[s])
+	}
+
+	b.SetBytes(int64(len(shards[0]) * 10))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err = r.Encode(shards)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Benchmark 10 data shards and 4 parity shards with 1MB each, for
+// a range of sizes given to WithKernelBlockSize.
+func BenchmarkEncodeKernelBlockSize(b *testing.B) {
+	for _, n := range []int{0, 1024, 4096, 16384, 65536} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			benchmarkEncodeKernelBlockSize(b, n)
+		})
+	}
+}
+
+func benchmarkEncodeBatch(b *testing.B, dataShards, parityShards, shardSize, objects int, batch bool) {
+	r, err := newExt(dataShards, parityShards)
+	if err != nil {
+		b.Fatal(err)
+	}
+	rand.Seed(0)
+	sets := make([][][]byte, objects)
+	for i := range sets {
+		sets[i] = make([][]byte, dataShards+parityShards)
+		for s := range sets[i] {
+			sets[i][s] = make([]byte, shardSize)
+		}
+		for s := 0; s < dataShards; s++ {
+			fillRandom(sets[i][s])
+		}
+	}
+
+	b.SetBytes(int64(shardSize * dataShards * objects))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if batch {
+			err = r.EncodeBatch(sets)
+			if err != nil {
+				b.Fatal(err)
+			}
+			continue
+		}
+		for _, shards := range sets {
+			err = r.Encode(shards)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// Compare a batch of small sets against encoding them one by one.
+func BenchmarkEncodeBatch10x4x4Kx100(b *testing.B) {
+	benchmarkEncodeBatch(b, 10, 4, 4096, 100, true)
+}
+
+func BenchmarkEncodeLoop10x4x4Kx100(b *testing.B) {
+	benchmarkEncodeBatch(b, 10, 4, 4096, 100, false)
+}
+
+func benchmarkEncodeInto(b *testing.B, dataShards, parityShards, shardSize int) {
+	r, err := newExt(dataShards, parityShards)
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := createSlice(dataShards, shardSize)
+	parity := createSlice(parityShards, shardSize)
+
+	rand.Seed(0)
+	for s := range data {
+		fillRandom(data[s])
+	}
+
+	b.SetBytes(int64(shardSize * dataShards))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err = r.EncodeInto(data, parity)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Benchmark 10 data shards and 4 parity shards with 4KB each.
+func BenchmarkEncodeInto10x4x4K(b *testing.B) {
+	benchmarkEncodeInto(b, 10, 4, 4096)
+}
+
+// Benchmark 10 data shards and 4 parity shards with 1MB each.
+func BenchmarkEncodeInto10x4x1M(b *testing.B) {
+	benchmarkEncodeInto(b, 10, 4, 1024*1024)
+}
+
+func benchmarkVerify(b *testing.B, dataShards, parityShards, shardSize int) {
+	r, err := New(dataShards, parityShards)
+	if err != nil {
+		b.Fatal(err)
+	}
+	shards := make([][]byte, parityShards+dataShards)
+	for s := range shards {
+		shards[s] = make([]byte, shardSize)
+	}
+
+	rand.Seed(0)
+	for s := 0; s < dataShards; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(shardSize * dataShards))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err = r.Verify(shards)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkUpdate(b *testing.B, dataShards, parityShards, shardSize int) {
+	r, err := newExt(dataShards, parityShards)
+	if err != nil {
+		b.Fatal(err)
+	}
+	shards := make([][]byte, parityShards+dataShards)
+	for s := range shards {
+		shards[s] = make([]byte, shardSize)
+	}
+	rand.Seed(0)
+	for s := 0; s < dataShards; s++ {
+		fillRandom(shards[s])
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		b.Fatal(err)
+	}
+	newData := make([]byte, shardSize)
+	fillRandom(newData)
 
-// Benchmark 10 data shards and 4 parity shards with 1MB each.
-func BenchmarkEncode10x4x1M(b *testing.B) {
-	benchmarkEncode(b, 10, 4, 1024*1024)
+	b.SetBytes(int64(shardSize))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err = r.Update(shards, 0, shards[0], newData)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
 }
 
-// Benchmark 50 data shards and 20 parity shards with 1MB each.
-func BenchmarkEncode50x20x1M(b *testing.B) {
-	benchmarkEncode(b, 50, 20, 1024*1024)
+func BenchmarkUpdate10x4x4K(b *testing.B) {
+	benchmarkUpdate(b, 10, 4, 4096)
 }
 
-// Benchmark 17 data shards and 3 parity shards with 16MB each.
-func BenchmarkEncode17x3x16M(b *testing.B) {
-	benchmarkEncode(b, 17, 3, 16*1024*1024)
+func BenchmarkUpdate10x4x1M(b *testing.B) {
+	benchmarkUpdate(b, 10, 4, 1024*1024)
 }
 
-func benchmarkVerify(b *testing.B, dataShards, parityShards, shardSize int) {
-	r, err := New(dataShards, parityShards)
+func benchmarkVerifyInto(b *testing.B, dataShards, parityShards, shardSize int) {
+	r, err := newExt(dataShards, parityShards)
 	if err != nil {
 		b.Fatal(err)
 	}
-	shards := make([][]byte, parityShards+dataShards)
-	for s := range shards {
-		shards[s] = make([]byte, shardSize)
-	}
+	shards := createSlice(parityShards+dataShards, shardSize)
+	scratch := createSlice(parityShards, shardSize)
 
 	rand.Seed(0)
 	for s := 0; s < dataShards; s++ {
@@ -439,15 +2805,21 @@ func benchmarkVerify(b *testing.B, dataShards, parityShards, shardSize int) {
 	}
 
 	b.SetBytes(int64(shardSize * dataShards))
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err = r.Verify(shards)
+		_, err = r.VerifyInto(shards, scratch)
 		if err != nil {
 			b.Fatal(err)
 		}
 	}
 }
 
+// Benchmark 10 data slices with 4 parity slices holding 4KB each
+func BenchmarkVerifyInto10x4x4K(b *testing.B) {
+	benchmarkVerifyInto(b, 10, 4, 4096)
+}
+
 // Benchmark 10 data slices with 2 parity slices holding 10000 bytes each
 func BenchmarkVerify10x2x10000(b *testing.B) {
 	benchmarkVerify(b, 10, 2, 10000)
@@ -483,6 +2855,33 @@ func BenchmarkVerify10x4x16M(b *testing.B) {
 	benchmarkVerify(b, 10, 4, 16*1024*1024)
 }
 
+func TestZeroParity(t *testing.T) {
+	enc, err := New(5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data = make([]byte, 250000)
+	fillRandom(data)
+	shards, _ := enc.Split(data)
+	err = enc.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+	err = enc.Reconstruct(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards[0] = nil
+	err = enc.Reconstruct(shards)
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+}
+
 func TestEncoderReconstruct(t *testing.T) {
 	// Create some sample data
 	var data = make([]byte, 250000)
@@ -580,7 +2979,7 @@ func TestSplitJoin(t *testing.T) {
 	}
 
 	err = enc.Join(buf, [][]byte{}, 0)
-	if err != ErrTooFewShards {
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
 
@@ -590,6 +2989,267 @@ func TestSplitJoin(t *testing.T) {
 	}
 }
 
+func TestSplitData(t *testing.T) {
+	enc, err := newExt(5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{1, 4, 5, 1001, 250000} {
+		data := make([]byte, size)
+		fillRandom(data)
+		split, err := enc.Split(append([]byte{}, data...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards, err := enc.SplitData(append([]byte{}, data...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(shards) != 5 {
+			t.Fatalf("size %d: expected 5 shards, got %d", size, len(shards))
+		}
+		for i := range shards {
+			if !bytes.Equal(shards[i], split[i]) {
+				t.Errorf("size %d: shard %d differs from Split", size, i)
+			}
+		}
+
+		// Nil parity is allocated by Encode.
+		shards = append(shards, make([][]byte, 3)...)
+		err = enc.Encode(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = enc.Encode(split)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 5; i < 8; i++ {
+			if !bytes.Equal(shards[i], split[i]) {
+				t.Errorf("size %d: parity %d mismatch", size, i)
+			}
+		}
+	}
+
+	_, err = enc.SplitData([]byte{})
+	if err != ErrShortData {
+		t.Errorf("expected %v, got %v", ErrShortData, err)
+	}
+}
+
+func TestJoinOutSize(t *testing.T) {
+	enc, err := New(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 1001)
+	fillRandom(data)
+	shards, err := enc.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	capacity := len(shards[0]) * 4
+	if capacity == len(data) {
+		t.Fatal("expected padding")
+	}
+	padded := append(append([]byte{}, data...), make([]byte, capacity-len(data))...)
+
+	for _, size := range []int{0, 1, len(shards[0]), len(shards[0]) + 1, len(data), capacity - 1, capacity} {
+		var buf bytes.Buffer
+		err = enc.Join(&buf, shards, size)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(buf.Bytes(), padded[:size]) {
+			t.Fatalf("size %d: got %d bytes, mismatch", size, buf.Len())
+		}
+	}
+	for _, size := range []int{capacity + 1, capacity * 2} {
+		var buf bytes.Buffer
+		err = enc.Join(&buf, shards, size)
+		if err != ErrShortData {
+			t.Errorf("size %d: expected %v, got %v", size, ErrShortData, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("size %d: %d bytes written on error", size, buf.Len())
+		}
+	}
+}
+
+func TestJoinEmpty(t *testing.T) {
+	enc, err := newExt(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An empty object has no data, so the shards may be empty too.
+	for _, size := range []int{0, 16} {
+		shards := make([][]byte, 6)
+		for i := range shards {
+			shards[i] = make([]byte, size)
+		}
+		var buf bytes.Buffer
+		err = enc.Join(&buf, shards, 0)
+		if err != nil {
+			t.Fatalf("shard size %d: %v", size, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("shard size %d: %d bytes written", size, buf.Len())
+		}
+	}
+
+	data := make([]byte, 100)
+	shards, err := enc.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = enc.Join(&buf, shards, -1)
+	if err != ErrInvalidSize {
+		t.Errorf("expected %v, got %v", ErrInvalidSize, err)
+	}
+	err = enc.JoinReordered(&buf, shards, []int{0, 1, 2, 3, 4, 5}, -1)
+	if err != ErrInvalidSize {
+		t.Errorf("expected %v, got %v", ErrInvalidSize, err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("%d bytes written on error", buf.Len())
+	}
+}
+
+func TestJoinVerified(t *testing.T) {
+	r, err := newExt(5, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 1003)
+	fillRandom(data)
+	shards, err := r.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+
+	// The hash must be reset before use.
+	h := sha256.New()
+	h.Write([]byte("previous"))
+	var buf bytes.Buffer
+	err = r.JoinVerified(&buf, shards, len(data), sum[:], h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("joined data mismatch")
+	}
+
+	err = r.JoinVerified(ioutil.Discard, shards, len(data)-1, sum[:], h)
+	if err != ErrHashMismatch {
+		t.Errorf("expected %v, got %v", ErrHashMismatch, err)
+	}
+	shards[2][10] ^= 1
+	err = r.JoinVerified(ioutil.Discard, shards, len(data), sum[:], h)
+	if err != ErrHashMismatch {
+		t.Errorf("expected %v, got %v", ErrHashMismatch, err)
+	}
+	err = r.JoinVerified(ioutil.Discard, shards, len(data)*2, sum[:], h)
+	if err != ErrShortData {
+		t.Errorf("expected %v, got %v", ErrShortData, err)
+	}
+}
+
+func TestJoinReordered(t *testing.T) {
+	r, err := newExt(5, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 1003)
+	fillRandom(data)
+	shards, err := r.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := rand.Perm(7)
+	mixed := make([][]byte, 7)
+	for i, idx := range order {
+		mixed[i] = shards[idx]
+	}
+	var buf bytes.Buffer
+	err = r.JoinReordered(&buf, mixed, order, len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("joined data mismatch")
+	}
+
+	// Only data shards.
+	buf.Reset()
+	err = r.JoinReordered(&buf, [][]byte{shards[3], shards[1], shards[0], shards[4], shards[2]}, []int{3, 1, 0, 4, 2}, len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("joined data mismatch")
+	}
+
+	for _, bad := range [][]int{{0, 1, 2, 3, 4, 5}, {0, 1, 2, 3, 4, 5, 5}, {0, 1, 2, 3, 4, 5, 7}, {-1, 1, 2, 3, 4, 5, 6}} {
+		err = r.JoinReordered(&buf, mixed, bad, len(data))
+		if err != ErrInvalidOrder {
+			t.Errorf("order %v: expected %v, got %v", bad, ErrInvalidOrder, err)
+		}
+	}
+	err = r.JoinReordered(&buf, mixed[:4], []int{0, 1, 2, 3}, len(data))
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+}
+
+func TestSplitPaddedJoinTrim(t *testing.T) {
+	enc, _ := newExt(5, 3)
+	for _, size := range []int{1, 4, 5, 6, 499, 500, 501, 250000} {
+		var data = make([]byte, size)
+		fillRandom(data)
+
+		shards, padding, err := enc.SplitPadded(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := len(shards[0])*5 - size; padding != want {
+			t.Fatalf("size %d: padding %d, want %d", size, padding, want)
+		}
+
+		buf := new(bytes.Buffer)
+		err = enc.JoinTrim(buf, shards, padding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Fatalf("size %d: recovered data does not match original", size)
+		}
+	}
+
+	shards, _, _ := enc.SplitPadded(make([]byte, 100))
+	err := enc.JoinTrim(new(bytes.Buffer), shards, len(shards[0])*5)
+	if err != ErrInvalidPadding {
+		t.Errorf("expected %v, got %v", ErrInvalidPadding, err)
+	}
+	err = enc.JoinTrim(new(bytes.Buffer), shards, -1)
+	if err != ErrInvalidPadding {
+		t.Errorf("expected %v, got %v", ErrInvalidPadding, err)
+	}
+	err = enc.JoinTrim(new(bytes.Buffer), shards[:3], 0)
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+	_, _, err = enc.SplitPadded([]byte{})
+	if err != ErrShortData {
+		t.Errorf("expected %v, got %v", ErrShortData, err)
+	}
+}
+
 func TestCodeSomeShards(t *testing.T) {
 	var data = make([]byte, 250000)
 	fillRandom(data)
@@ -624,14 +3284,19 @@ func TestNew(t *testing.T) {
 		err          error
 	}{
 		{127, 127, nil},
+		{128, 128, nil},
+		{255, 1, nil},
+		{1, 0, nil},
+		{129, 128, ErrMaxShardNum},
 		{256, 256, ErrMaxShardNum},
 
 		{0, 1, ErrInvShardNum},
-		{1, 0, ErrInvShardNum},
+		{1, -1, ErrInvShardNum},
+		{-1, 1, ErrInvShardNum},
 		{257, 1, ErrMaxShardNum},
 
-		// overflow causes r.Shards to be negative
-		{256, int(^uint(0) >> 1), errInvalidRowSize},
+		// overflow would cause r.Shards to be negative
+		{256, int(^uint(0) >> 1), ErrMaxShardNum},
 	}
 	for _, test := range tests {
 		_, err := New(test.data, test.parity)
@@ -639,4 +3304,139 @@ func TestNew(t *testing.T) {
 			t.Errorf("New(%v, %v): expected %v, got %v", test.data, test.parity, test.err, err)
 		}
 	}
+
+	// Reconfigure must validate the same way.
+	r, err := newExt(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		err := r.Reconfigure(test.data, test.parity)
+		if err != test.err {
+			t.Errorf("Reconfigure(%v, %v): expected %v, got %v", test.data, test.parity, test.err, err)
+		}
+	}
+}
+
+func TestNonSystematic(t *testing.T) {
+	const dataShards, parityShards = 5, 3
+	r, err := newExt(dataShards, parityShards, WithNonSystematic())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 5003)
+	fillRandom(data)
+	orig, _ := r.Split(append([]byte{}, data...))
+	orig = orig[:dataShards]
+	shards, err := r.Split(append([]byte{}, data...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, shard := range shards[:dataShards] {
+		if bytes.Equal(shard, orig[i]) {
+			t.Fatalf("data shard %d not mixed", i)
+		}
+	}
+	ok, err := r.Verify(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("verification failed")
+	}
+
+	// Any dataShards shards must recover the data.
+	for mask := 0; mask < 1<<(dataShards+parityShards); mask++ {
+		present := 0
+		test := make([][]byte, len(shards))
+		for i := range shards {
+			if mask&(1<<uint(i)) != 0 {
+				present++
+				test[i] = append([]byte{}, shards[i]...)
+			}
+		}
+		if present != dataShards {
+			continue
+		}
+		err = r.DecodeData(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		err = r.Join(&buf, test, len(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Fatalf("shards %08b: decoded data mismatch", mask)
+		}
+	}
+
+	err = r.Update(shards, 0, shards[0], shards[1])
+	if err != ErrNonSystematic {
+		t.Errorf("expected %v, got %v", ErrNonSystematic, err)
+	}
+	_, err = New(100, 57, WithNonSystematic())
+	if err != ErrMaxShardNum {
+		t.Errorf("expected %v, got %v", ErrMaxShardNum, err)
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	r, err := newExt(10, 4, WithCauchyMatrix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 10000)
+	fillRandom(data)
+	for _, conf := range [][2]int{{5, 2}, {17, 3}, {3, 0}, {10, 4}} {
+		err = r.Reconfigure(conf[0], conf[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Compare to a fresh encoder with the same options.
+		want, err := New(conf[0], conf[1], WithCauchyMatrix())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := r.Split(append([]byte{}, data...))
+		exp, _ := want.Split(append([]byte{}, data...))
+		if len(got) != conf[0]+conf[1] {
+			t.Fatalf("expected %d shards, got %d", conf[0]+conf[1], len(got))
+		}
+		err = r.Encode(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want.Encode(exp)
+		for i := range got {
+			if !bytes.Equal(got[i], exp[i]) {
+				t.Fatalf("%v: shard %d mismatch", conf, i)
+			}
+		}
+		if conf[1] > 0 {
+			got[0] = nil
+			err = r.Reconstruct(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got[0], exp[0]) {
+				t.Fatalf("%v: reconstruct mismatch", conf)
+			}
+		}
+	}
+
+	// The encoder is unchanged on error.
+	err = r.Reconfigure(200, 100)
+	if err != ErrMaxShardNum {
+		t.Errorf("expected %v, got %v", ErrMaxShardNum, err)
+	}
+	shards, _ := r.Split(data)
+	if len(shards) != 14 {
+		t.Fatalf("expected 14 shards, got %d", len(shards))
+	}
 }
@@ -0,0 +1,135 @@
+package reedsolomon
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// ShardFileHeaderSize is the number of bytes WriteShard writes in
+// front of the shard data.
+//
+// The header is stored big endian, and contains:
+//
+//	magic     [4]byte // "RSSF"
+//	version   uint8   // currently 1
+//	reserved  [3]byte // must be zero
+//	index     uint32  // index of the shard in the encoded set
+//	size      uint64  // number of data bytes following the header
+//	dataCRC   uint32  // CRC32C (Castagnoli) of the data
+//	headerCRC uint32  // CRC32C of the preceding header bytes
+const ShardFileHeaderSize = 28
+
+const shardFileVersion = 1
+
+var shardFileMagic = [4]byte{'R', 'S', 'S', 'F'}
+
+// ErrInvalidShardFile is returned by ReadShard if the header is not
+// a valid shard file header.
+var ErrInvalidShardFile = errors.New("invalid shard file header")
+
+// ErrShardChecksum is returned by ReadShard if the shard data does
+// not match the checksum stored in the header.
+var ErrShardChecksum = errors.New("shard data does not match checksum")
+
+// WriteShard writes the shard at index idx in a self-describing format,
+// which can be read back by ReadShard.
+// The shard is preceded by a header of ShardFileHeaderSize bytes,
+// holding the index, the size and a checksum of the data.
+func WriteShard(w io.Writer, idx int, data []byte) error {
+	if idx < 0 || uint64(idx) > 0xffffffff {
+		return ErrInvalidShardIndex
+	}
+	var hdr [ShardFileHeaderSize]byte
+	copy(hdr[:4], shardFileMagic[:])
+	hdr[4] = shardFileVersion
+	binary.BigEndian.PutUint32(hdr[8:], uint32(idx))
+	binary.BigEndian.PutUint64(hdr[12:], uint64(len(data)))
+	binary.BigEndian.PutUint32(hdr[20:], crc32.Checksum(data, castagnoli))
+	binary.BigEndian.PutUint32(hdr[24:], crc32.Checksum(hdr[:24], castagnoli))
+	_, err := w.Write(hdr[:])
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ReadShard reads a shard written by WriteShard, and returns the index
+// and data of the shard.
+//
+// The header is validated before the data is read, so a corrupted
+// size never causes a large allocation, and ErrInvalidShardFile is
+// returned if it is damaged. If the data is truncated,
+// io.ErrUnexpectedEOF is returned, and if it does not match its
+// checksum, ErrShardChecksum is returned.
+// Only the shard is read from r, so several shards may be stored
+// after each other.
+func ReadShard(r io.Reader) (idx int, data []byte, err error) {
+	var hdr [ShardFileHeaderSize]byte
+	_, err = io.ReadFull(r, hdr[:])
+	if err != nil {
+		return 0, nil, err
+	}
+	if crc32.Checksum(hdr[:24], castagnoli) != binary.BigEndian.Uint32(hdr[24:]) {
+		return 0, nil, ErrInvalidShardFile
+	}
+	if [4]byte{hdr[0], hdr[1], hdr[2], hdr[3]} != shardFileMagic || hdr[4] != shardFileVersion ||
+		hdr[5]|hdr[6]|hdr[7] != 0 {
+		return 0, nil, ErrInvalidShardFile
+	}
+	size := binary.BigEndian.Uint64(hdr[12:])
+	if size > uint64(maxInt) {
+		return 0, nil, ErrInvalidShardFile
+	}
+	data = make([]byte, size)
+	_, err = io.ReadFull(r, data)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if crc32.Checksum(data, castagnoli) != binary.BigEndian.Uint32(hdr[20:]) {
+		return 0, nil, ErrShardChecksum
+	}
+	return int(binary.BigEndian.Uint32(hdr[8:])), data, nil
+}
+
+// maxInt is the largest value of an int.
+const maxInt = int(^uint(0) >> 1)
+
+// ReadShards reads one shard written by WriteShard from each reader,
+// and returns them placed at their index in a set of totalShards
+// shards, ready to be given to Reconstruct.
+//
+// Readers may be given in any order, and nil readers are skipped.
+// Shards that are truncated, damaged or do not match their checksum
+// are left nil, so they will be reconstructed.
+// Errors other than that are returned as a StreamReadError.
+// A shard with an index outside the set, or an index read twice,
+// returns ErrInvalidShardIndex.
+func ReadShards(readers []io.Reader, totalShards int) ([][]byte, error) {
+	shards := make([][]byte, totalShards)
+	seen := make([]bool, totalShards)
+	for i, r := range readers {
+		if r == nil {
+			continue
+		}
+		idx, data, err := ReadShard(r)
+		switch err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF, ErrInvalidShardFile, ErrShardChecksum:
+			continue
+		default:
+			return nil, StreamReadError{Err: err, Stream: i}
+		}
+		if idx < 0 || idx >= totalShards || seen[idx] {
+			return nil, ErrInvalidShardIndex
+		}
+		seen[idx] = true
+		shards[idx] = data
+	}
+	return shards, nil
+}
@@ -0,0 +1,97 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestShardFile(t *testing.T) {
+	r, err := New(5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 5000)
+	fillRandom(data)
+	shards, err := r.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Encode(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make([][]byte, len(shards))
+	for i, shard := range shards {
+		var buf bytes.Buffer
+		err = WriteShard(&buf, i, shard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != ShardFileHeaderSize+len(shard) {
+			t.Fatalf("unexpected file size %d", buf.Len())
+		}
+		files[i] = buf.Bytes()
+	}
+
+	idx, got, err := ReadShard(bytes.NewReader(files[3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx != 3 || !bytes.Equal(got, shards[3]) {
+		t.Fatal("read shard mismatch")
+	}
+
+	// Damage the header, the data, and truncate a file.
+	bad := append([]byte{}, files[0]...)
+	bad[9]++
+	_, _, err = ReadShard(bytes.NewReader(bad))
+	if err != ErrInvalidShardFile {
+		t.Errorf("expected %v, got %v", ErrInvalidShardFile, err)
+	}
+	corrupt := append([]byte{}, files[1]...)
+	corrupt[ShardFileHeaderSize+10]++
+	_, _, err = ReadShard(bytes.NewReader(corrupt))
+	if err != ErrShardChecksum {
+		t.Errorf("expected %v, got %v", ErrShardChecksum, err)
+	}
+	_, _, err = ReadShard(bytes.NewReader(files[2][:len(files[2])-1]))
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("expected %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	// The damaged shards are reconstructed.
+	readers := []io.Reader{
+		bytes.NewReader(files[7]), bytes.NewReader(bad), nil,
+		bytes.NewReader(corrupt), bytes.NewReader(files[2][:100]),
+		bytes.NewReader(files[3]), bytes.NewReader(files[4]),
+		bytes.NewReader(files[5]), bytes.NewReader(files[6]),
+	}
+	set, err := ReadShards(readers, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{0, 1, 2} {
+		if set[i] != nil {
+			t.Fatalf("shard %d should be missing", i)
+		}
+	}
+	err = r.Reconstruct(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range shards {
+		if !bytes.Equal(set[i], shards[i]) {
+			t.Fatalf("shard %d mismatch", i)
+		}
+	}
+
+	_, err = ReadShards([]io.Reader{bytes.NewReader(files[3]), bytes.NewReader(files[3])}, 8)
+	if err != ErrInvalidShardIndex {
+		t.Errorf("expected %v, got %v", ErrInvalidShardIndex, err)
+	}
+	_, err = ReadShards([]io.Reader{bytes.NewReader(files[7])}, 7)
+	if err != ErrInvalidShardIndex {
+		t.Errorf("expected %v, got %v", ErrInvalidShardIndex, err)
+	}
+}
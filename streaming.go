@@ -44,6 +44,14 @@ type StreamEncoder interface {
 	// StreamWriteError will be returned.
 	Encode(data []io.Reader, parity []io.Writer) error
 
+	// EncodeAppend encodes parity shards for data stored in a single
+	// object, laid out as the data shards written by Split.
+	//
+	// dataSize is the size of the data in src. Each data shard is read
+	// directly from src, so the data is never split into separate
+	// shards. The parity shards are written to parity.
+	EncodeAppend(src io.ReaderAt, dataSize int64, parity []io.Writer) error
+
 	// Verify returns true if the parity shards contain correct data.
 	//
 	// The number of shards must match the number total data+parity shards
@@ -64,7 +72,7 @@ type StreamEncoder interface {
 	// If both are provided 'ErrReconstructMismatch' is returned.
 	//
 	// If there are too few shards to reconstruct the missing
-	// ones, ErrTooFewShards will be returned.
+	// ones, ErrTooManyFailures will be returned.
 	//
 	// The reconstructed shard set is complete, but integrity is not verified.
 	// Use the Verify function to check if data set is ok.
@@ -86,8 +94,9 @@ type StreamEncoder interface {
 	// Only the data shards are considered.
 	//
 	// You must supply the exact output size you want.
-	// If there are to few shards given, ErrTooFewShards will be returned.
+	// If there are to few shards given, ErrShardCount will be returned.
 	// If the total data size is less than outSize, ErrShortData will be returned.
+	// A negative outSize returns ErrInvalidSize.
 	Join(dst io.Writer, shards []io.Reader, outSize int64) error
 }
 
@@ -145,8 +154,8 @@ type rsStream struct {
 // the number of data shards and parity shards that
 // you want to use. You can reuse this encoder.
 // Note that the maximum number of data shards is 256.
-func NewStream(dataShards, parityShards int) (StreamEncoder, error) {
-	enc, err := New(dataShards, parityShards)
+func NewStream(dataShards, parityShards int, o ...Option) (StreamEncoder, error) {
+	enc, err := New(dataShards, parityShards, o...)
 	if err != nil {
 		return nil, err
 	}
@@ -161,8 +170,8 @@ func NewStream(dataShards, parityShards int) (StreamEncoder, error) {
 // the number of data shards and parity shards given.
 //
 // This functions as 'NewStream', but allows you to enable CONCURRENT reads and writes.
-func NewStreamC(dataShards, parityShards int, conReads, conWrites bool) (StreamEncoder, error) {
-	enc, err := New(dataShards, parityShards)
+func NewStreamC(dataShards, parityShards int, conReads, conWrites bool, o ...Option) (StreamEncoder, error) {
+	enc, err := New(dataShards, parityShards, o...)
 	if err != nil {
 		return nil, err
 	}
@@ -180,11 +189,7 @@ func NewStreamC(dataShards, parityShards int, conReads, conWrites bool) (StreamE
 }
 
 func createSlice(n, length int) [][]byte {
-	out := make([][]byte, n)
-	for i := range out {
-		out[i] = make([]byte, length)
-	}
-	return out
+	return AllocAligned(n, length)
 }
 
 // Encodes parity shards for a set of data shards.
@@ -204,11 +209,11 @@ func createSlice(n, length int) [][]byte {
 // StreamWriteError will be returned.
 func (r rsStream) Encode(data []io.Reader, parity []io.Writer) error {
 	if len(data) != r.r.DataShards {
-		return ErrTooFewShards
+		return ErrShardCount
 	}
 
 	if len(parity) != r.r.ParityShards {
-		return ErrTooFewShards
+		return ErrShardCount
 	}
 
 	all := createSlice(r.r.Shards, r.bs)
@@ -241,6 +246,42 @@ func (r rsStream) Encode(data []io.Reader, parity []io.Writer) error {
 	}
 }
 
+// EncodeAppend encodes parity shards for data stored in a single
+// object, which can then be stored together with the parity shards.
+//
+// The data shards are the consecutive parts of src that Split would
+// write, so each data shard is (dataSize+DataShards-1)/DataShards
+// bytes, and the last data shard is padded with zeros. Other than
+// the padding, nothing outside the first dataSize bytes of src is read.
+//
+// The parity shards are written to parity, and the number of bytes
+// written will be the size of a data shard.
+// If src returns an error, a StreamReadError type error will be
+// returned. If a parity writer returns an error, a StreamWriteError
+// will be returned.
+func (r rsStream) EncodeAppend(src io.ReaderAt, dataSize int64, parity []io.Writer) error {
+	if dataSize <= 0 {
+		return ErrShortData
+	}
+	perShard := (dataSize + int64(r.r.DataShards) - 1) / int64(r.r.DataShards)
+	data := make([]io.Reader, r.r.DataShards)
+	for i := range data {
+		start := int64(i) * perShard
+		n := dataSize - start
+		if n > perShard {
+			n = perShard
+		}
+		if n < 0 {
+			n = 0
+		}
+		data[i] = io.NewSectionReader(src, start, n)
+		if n < perShard {
+			data[i] = io.MultiReader(data[i], bytes.NewReader(make([]byte, perShard-n)))
+		}
+	}
+	return r.Encode(data, parity)
+}
+
 // Trim the shards so they are all the same size
 func trimShards(in [][]byte, size int) [][]byte {
 	for i := range in {
@@ -268,8 +309,11 @@ func readShards(dst [][]byte, in []io.Reader) error {
 		// The error is EOF only if no bytes were read.
 		// If an EOF happens after reading some but not all the bytes,
 		// ReadFull returns ErrUnexpectedEOF.
+		// All streams must return the same amount, including full
+		// reads, so a stream ending early is never mistaken for
+		// the end of all streams.
 		switch err {
-		case io.ErrUnexpectedEOF, io.EOF:
+		case nil, io.ErrUnexpectedEOF, io.EOF:
 			if size < 0 {
 				size = n
 			} else if n != size {
@@ -277,8 +321,6 @@ func readShards(dst [][]byte, in []io.Reader) error {
 				return ErrShardSize
 			}
 			dst[i] = dst[i][0:n]
-		case nil:
-			continue
 		default:
 			return StreamReadError{Err: err, Stream: i}
 		}
@@ -344,7 +386,7 @@ func cReadShards(dst [][]byte, in []io.Reader) error {
 	size := -1
 	for r := range res {
 		switch r.err {
-		case io.ErrUnexpectedEOF, io.EOF:
+		case nil, io.ErrUnexpectedEOF, io.EOF:
 			if size < 0 {
 				size = r.size
 			} else if r.size != size {
@@ -352,7 +394,6 @@ func cReadShards(dst [][]byte, in []io.Reader) error {
 				return ErrShardSize
 			}
 			dst[r.n] = dst[r.n][0:r.size]
-		case nil:
 		default:
 			return StreamReadError{Err: r.err, Stream: r.n}
 		}
@@ -409,7 +450,7 @@ func cWriteShards(out []io.Writer, in [][]byte) error {
 // will be returned.
 func (r rsStream) Verify(shards []io.Reader) (bool, error) {
 	if len(shards) != r.r.Shards {
-		return false, ErrTooFewShards
+		return false, ErrShardCount
 	}
 
 	read := 0
@@ -448,28 +489,45 @@ var ErrReconstructMismatch = errors.New("valid shards and fill shards are mutual
 // An index cannot contain both non-nil 'valid' and 'fill' entry.
 //
 // If there are too few shards to reconstruct the missing
-// ones, ErrTooFewShards will be returned.
+// ones, ErrTooManyFailures will be returned.
 //
 // The reconstructed shard set is complete, but integrity is not verified.
 // Use the Verify function to check if data set is ok.
 func (r rsStream) Reconstruct(valid []io.Reader, fill []io.Writer) error {
 	if len(valid) != r.r.Shards {
-		return ErrTooFewShards
+		return ErrShardCount
 	}
 	if len(fill) != r.r.Shards {
-		return ErrTooFewShards
+		return ErrShardCount
 	}
 
-	all := createSlice(r.r.Shards, r.bs)
 	for i := range valid {
 		if valid[i] != nil && fill[i] != nil {
 			return ErrReconstructMismatch
 		}
 	}
 
+	var all [][]byte
+	var blocks <-chan readBlock
+	var free chan<- [][]byte
+	if r.r.o.readAhead > 0 {
+		var stop func()
+		blocks, free, stop = r.readAhead(valid, r.r.o.readAhead)
+		// Never return while the readers or buffers are in use.
+		defer stop()
+	} else {
+		all = createSlice(r.r.Shards, r.bs)
+	}
+
 	read := 0
 	for {
-		err := r.readShards(all, valid)
+		var err error
+		if blocks != nil {
+			b := <-blocks
+			all, err = b.shards, b.err
+		} else {
+			err = r.readShards(all, valid)
+		}
 		if err == io.EOF {
 			if read == 0 {
 				return ErrShardNoData
@@ -482,7 +540,8 @@ func (r rsStream) Reconstruct(valid []io.Reader, fill []io.Writer) error {
 		read += shardSize(all)
 		all = trimShards(all, shardSize(all))
 
-		err = r.r.Reconstruct(all)
+		// Every fill stream must be written, so never be lazy.
+		err = r.r.reconstruct(all, false)
 		if err != nil {
 			return err
 		}
@@ -490,7 +549,59 @@ func (r rsStream) Reconstruct(valid []io.Reader, fill []io.Writer) error {
 		if err != nil {
 			return err
 		}
+		if free != nil {
+			free <- all
+		}
+	}
+}
+
+// readBlock is a block of shards read by readAhead.
+type readBlock struct {
+	shards [][]byte
+	err    error
+}
+
+// readAhead reads blocks from in with readShards in a separate
+// goroutine, so up to depth blocks are read while the caller is
+// processing the previous block.
+// Blocks are received from the first channel, and must be sent
+// back on the second when the caller is done with them.
+// Reading stops after the first error, including io.EOF, or when
+// stop is called. stop returns when the goroutine has exited, so the
+// readers are no longer used, and must be called exactly once.
+func (r rsStream) readAhead(in []io.Reader, depth int) (<-chan readBlock, chan<- [][]byte, func()) {
+	full := make(chan readBlock, depth)
+	free := make(chan [][]byte, depth+1)
+	for i := 0; i <= depth; i++ {
+		free <- createSlice(len(in), r.bs)
+	}
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			var shards [][]byte
+			select {
+			case shards = <-free:
+			case <-done:
+				return
+			}
+			err := r.readShards(shards, in)
+			select {
+			case full <- readBlock{shards: shards, err: err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	stop := func() {
+		close(done)
+		<-exited
 	}
+	return full, free, stop
 }
 
 // Join the shards and write the data segment to dst.
@@ -498,12 +609,17 @@ func (r rsStream) Reconstruct(valid []io.Reader, fill []io.Writer) error {
 // Only the data shards are considered.
 //
 // You must supply the exact output size you want.
-// If there are to few shards given, ErrTooFewShards will be returned.
+// If there are to few shards given, ErrShardCount will be returned.
 // If the total data size is less than outSize, ErrShortData will be returned.
+// A negative outSize returns ErrInvalidSize, while an outSize of 0 writes
+// nothing, so empty objects can be joined from empty shards.
 func (r rsStream) Join(dst io.Writer, shards []io.Reader, outSize int64) error {
+	if outSize < 0 {
+		return ErrInvalidSize
+	}
 	// Do we have enough shards?
 	if len(shards) < r.r.DataShards {
-		return ErrTooFewShards
+		return ErrShardCount
 	}
 
 	// Trim off parity shards if any
@@ -8,10 +8,12 @@ package reedsolomon
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"io/ioutil"
 	"math/rand"
 	"testing"
+	"testing/iotest"
 )
 
 func TestStreamEncoding(t *testing.T) {
@@ -45,11 +47,11 @@ func TestStreamEncoding(t *testing.T) {
 	}
 
 	err = r.Encode(toReaders(emptyBuffers(1)), toWriters(emptyBuffers(1)))
-	if err != ErrTooFewShards {
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
 	err = r.Encode(toReaders(emptyBuffers(10)), toWriters(emptyBuffers(1)))
-	if err != ErrTooFewShards {
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
 	err = r.Encode(toReaders(emptyBuffers(10)), toWriters(emptyBuffers(3)))
@@ -96,11 +98,11 @@ func TestStreamEncodingConcurrent(t *testing.T) {
 	}
 
 	err = r.Encode(toReaders(emptyBuffers(1)), toWriters(emptyBuffers(1)))
-	if err != ErrTooFewShards {
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
 	err = r.Encode(toReaders(emptyBuffers(10)), toWriters(emptyBuffers(1)))
-	if err != ErrTooFewShards {
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
 	err = r.Encode(toReaders(emptyBuffers(10)), toWriters(emptyBuffers(3)))
@@ -132,6 +134,51 @@ func randomBytes(n, length int) [][]byte {
 	return bufs
 }
 
+func TestStreamEncodeAppend(t *testing.T) {
+	r, err := NewStream(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := New(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{1, 9, 10, 1000, 100001, 1 << 20} {
+		data := make([]byte, size)
+		fillRandom(data)
+		// Data after dataSize must not be read.
+		src := append(append([]byte{}, data...), 0xff, 0xff, 0xff)
+
+		parity := emptyBuffers(3)
+		err = r.EncodeAppend(bytes.NewReader(src), int64(size), toWriters(parity))
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards, err := enc.Split(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = enc.Encode(shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range parity {
+			if !bytes.Equal(parity[i].Bytes(), shards[10+i]) {
+				t.Fatalf("size %d: parity %d mismatch", size, i)
+			}
+		}
+	}
+
+	err = r.EncodeAppend(bytes.NewReader(nil), 0, toWriters(emptyBuffers(3)))
+	if err != ErrShortData {
+		t.Errorf("expected %v, got %v", ErrShortData, err)
+	}
+	err = r.EncodeAppend(bytes.NewReader(make([]byte, 100)), 100, toWriters(emptyBuffers(2)))
+	if err != ErrShardCount {
+		t.Errorf("expected %v, got %v", ErrShardCount, err)
+	}
+}
+
 func toBuffers(in [][]byte) []*bytes.Buffer {
 	out := make([]*bytes.Buffer, len(in))
 	for i := range in {
@@ -251,16 +298,16 @@ func TestStreamReconstruct(t *testing.T) {
 	fill[11] = emptyBuffers(1)[0]
 
 	err = r.Reconstruct(all, fill)
-	if err != ErrTooFewShards {
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
 
 	err = r.Reconstruct(toReaders(emptyBuffers(3)), toWriters(emptyBuffers(3)))
-	if err != ErrTooFewShards {
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
 	err = r.Reconstruct(toReaders(emptyBuffers(13)), toWriters(emptyBuffers(3)))
-	if err != ErrTooFewShards {
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
 	err = r.Reconstruct(toReaders(emptyBuffers(13)), toWriters(emptyBuffers(13)))
@@ -273,6 +320,170 @@ func TestStreamReconstruct(t *testing.T) {
 	}
 }
 
+func TestStreamReconstructReadAhead(t *testing.T) {
+	for _, depth := range []int{1, 3} {
+		for _, concurrent := range []bool{false, true} {
+			r, err := NewStreamC(5, 3, concurrent, concurrent, WithReadAhead(depth))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Use many blocks, so the reads overlap.
+			r.(*rsStream).bs = 1000
+			shards := randomBytes(5, 10500)
+			parb := emptyBuffers(3)
+			err = r.Encode(toReaders(toBuffers(shards)), toWriters(parb))
+			if err != nil {
+				t.Fatal(err)
+			}
+			parity := toBytes(parb)
+
+			all := append(toReaders(toBuffers(shards)), toReaders(toBuffers(parity))...)
+			fill := make([]io.Writer, 8)
+			all[1], all[3], all[6] = nil, nil, nil
+			for _, i := range []int{1, 3, 6} {
+				fill[i] = emptyBuffers(1)[0]
+			}
+			err = r.Reconstruct(all, fill)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, i := range []int{1, 3} {
+				if !bytes.Equal(fill[i].(*bytes.Buffer).Bytes(), shards[i]) {
+					t.Errorf("depth %d: shard %d mismatch", depth, i)
+				}
+			}
+			if !bytes.Equal(fill[6].(*bytes.Buffer).Bytes(), parity[1]) {
+				t.Errorf("depth %d: shard 6 mismatch", depth)
+			}
+
+			// A failing stream stops the reconstruction.
+			all = append(toReaders(toBuffers(shards)), toReaders(toBuffers(parity))...)
+			all[2] = nil
+			errRead := errors.New("read failed")
+			all[4] = io.MultiReader(bytes.NewReader(shards[4][:2500]), iotest.ErrReader(errRead))
+			fill = make([]io.Writer, 8)
+			fill[2] = emptyBuffers(1)[0]
+			err = r.Reconstruct(all, fill)
+			if se, ok := err.(StreamReadError); !ok || se.Stream != 4 || se.Err != errRead {
+				t.Errorf("depth %d: expected read error on stream 4, got %v", depth, err)
+			}
+
+			// A failing writer stops the reconstruction, and the
+			// readers are no longer read when Reconstruct returns.
+			readers := make([]*stopReader, 8)
+			all = make([]io.Reader, 8)
+			for i := range all {
+				b := shards[i%5]
+				if i >= 5 {
+					b = parity[i-5]
+				}
+				readers[i] = &stopReader{t: t, r: bytes.NewReader(b)}
+				all[i] = readers[i]
+			}
+			all[0] = nil
+			fill = make([]io.Writer, 8)
+			errWrite := errors.New("write failed")
+			fill[0] = &failWriter{n: 2500, err: errWrite}
+			err = r.Reconstruct(all, fill)
+			if se, ok := err.(StreamWriteError); !ok || se.Stream != 0 || se.Err != errWrite {
+				t.Errorf("depth %d: expected write error on stream 0, got %v", depth, err)
+			}
+			for _, rd := range readers {
+				rd.stopped = true
+			}
+		}
+	}
+}
+
+// stopReader reports reads after stopped is set.
+// The field is not synchronized, so a concurrent read is reported by
+// the race detector.
+type stopReader struct {
+	t       *testing.T
+	r       io.Reader
+	stopped bool
+}
+
+func (s *stopReader) Read(p []byte) (int, error) {
+	if s.stopped {
+		s.t.Error("read after stop")
+	}
+	return s.r.Read(p)
+}
+
+// failWriter accepts n bytes, and then returns err.
+type failWriter struct {
+	n   int
+	err error
+}
+
+func (f *failWriter) Write(p []byte) (int, error) {
+	if len(p) > f.n {
+		n := f.n
+		f.n = 0
+		return n, f.err
+	}
+	f.n -= len(p)
+	return len(p), nil
+}
+
+// The stream encoder fills every requested stream, also with WithLazyParity.
+func TestStreamLazyParity(t *testing.T) {
+	enc, err := NewStream(6, 4, WithLazyParity())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := randomBytes(6, 1000)
+	parb := emptyBuffers(4)
+	err = enc.Encode(toReaders(toBuffers(shards)), toWriters(parb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parity := toBytes(parb)
+	valid := append(toReaders(toBuffers(shards)), toReaders(toBuffers(parity))...)
+	fill := make([]io.Writer, 10)
+	valid[2], valid[9] = nil, nil
+	fill[2], fill[9] = new(bytes.Buffer), new(bytes.Buffer)
+	err = enc.Reconstruct(valid, fill)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fill[2].(*bytes.Buffer).Bytes(), shards[2]) {
+		t.Error("data shard not recreated")
+	}
+	if !bytes.Equal(fill[9].(*bytes.Buffer).Bytes(), parity[3]) {
+		t.Error("parity shard not recreated")
+	}
+}
+
+func TestStreamVerifyUnequal(t *testing.T) {
+	for _, concurrent := range []bool{false, true} {
+		r, err := NewStreamC(5, 2, concurrent, concurrent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.(*rsStream).bs = 1000
+		shards := randomBytes(5, 5000)
+		parb := emptyBuffers(2)
+		err = r.Encode(toReaders(toBuffers(shards)), toWriters(parb))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parity := toBytes(parb)
+
+		// Truncate at a block boundary, in the middle of a block,
+		// and before an untruncated stream.
+		for _, test := range []struct{ shard, size int }{{6, 4000}, {6, 4500}, {0, 3000}, {6, 0}} {
+			set := append(append([][]byte{}, shards...), parity...)
+			set[test.shard] = set[test.shard][:test.size]
+			ok, err := r.Verify(toReaders(toBuffers(set)))
+			if err != ErrShardSize || ok {
+				t.Errorf("concurrent %v, shard %d truncated to %d: expected %v, got %v, %v", concurrent, test.shard, test.size, ErrShardSize, ok, err)
+			}
+		}
+	}
+}
+
 func TestStreamVerify(t *testing.T) {
 	perShard := 10 << 20
 	if testing.Short() {
@@ -327,7 +538,7 @@ func TestStreamVerify(t *testing.T) {
 	}
 
 	_, err = r.Verify(toReaders(emptyBuffers(10)))
-	if err != ErrTooFewShards {
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
 
@@ -557,7 +768,7 @@ func TestStreamSplitJoin(t *testing.T) {
 	}
 
 	err = enc.Join(buf, toReaders(emptyBuffers(2)), 0)
-	if err != ErrTooFewShards {
+	if !errors.Is(err, ErrTooFewShards) {
 		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
 	}
 	bufs := toReaders(emptyBuffers(5))
@@ -578,6 +789,21 @@ func TestStreamSplitJoin(t *testing.T) {
 	if err != ErrShortData {
 		t.Errorf("expected %v, got %v", ErrShortData, err)
 	}
+
+	err = enc.Join(buf, toReaders(toBuffers(splits)), -1)
+	if err != ErrInvalidSize {
+		t.Errorf("expected %v, got %v", ErrInvalidSize, err)
+	}
+
+	// Empty shards join to an empty object.
+	buf.Reset()
+	err = enc.Join(buf, toReaders(emptyBuffers(5)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %d bytes", buf.Len())
+	}
 }
 
 func TestNewStream(t *testing.T) {
@@ -586,14 +812,15 @@ func TestNewStream(t *testing.T) {
 		err          error
 	}{
 		{127, 127, nil},
+		{1, 0, nil},
 		{256, 256, ErrMaxShardNum},
 
 		{0, 1, ErrInvShardNum},
-		{1, 0, ErrInvShardNum},
+		{1, -1, ErrInvShardNum},
 		{257, 1, ErrMaxShardNum},
 
-		// overflow causes r.Shards to be negative
-		{256, int(^uint(0) >> 1), errInvalidRowSize},
+		// overflow would cause r.Shards to be negative
+		{256, int(^uint(0) >> 1), ErrMaxShardNum},
 	}
 	for _, test := range tests {
 		_, err := NewStream(test.data, test.parity)